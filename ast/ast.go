@@ -4,6 +4,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/FollowTheProcess/monkey/lexer"
@@ -13,6 +14,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() lexer.Position // Position of the node's token in the source
 }
 
 // Statement represents a statement, i.e. a piece of syntax
@@ -54,6 +56,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos satisfies the ast.Node interface for our Program
+func (p *Program) Pos() lexer.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return lexer.Position{}
+}
+
 // LetStatement is our object responsible for e.g. 'let x = 5;'
 type LetStatement struct {
 	Token lexer.Token // The 'LET' token
@@ -63,6 +73,7 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() lexer.Position  { return ls.Token.Pos }
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -89,6 +100,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() lexer.Position  { return i.Token.Pos }
 
 func (i *Identifier) String() string {
 	return i.Value
@@ -102,6 +114,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() lexer.Position  { return rs.Token.Pos }
 
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -117,6 +130,74 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement is our object responsible for e.g. 'while (x < 10) { ... }'
+type WhileStatement struct {
+	Token     lexer.Token // The 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() lexer.Position  { return ws.Token.Pos }
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement is our object responsible for e.g. 'for (x in [1, 2, 3]) { ... }'
+type ForStatement struct {
+	Token    lexer.Token // The 'for' token
+	Variable *Identifier // Bound to each element in turn
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() lexer.Position  { return fs.Token.Pos }
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement is our object responsible for 'break;', leaving the innermost loop
+type BreakStatement struct {
+	Token lexer.Token // The 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() lexer.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement is our object responsible for 'continue;', skipping
+// to the next iteration of the innermost loop
+type ContinueStatement struct {
+	Token lexer.Token // The 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() lexer.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.TokenLiteral() + ";" }
+
 // ExpressionStatement is our object responsible for e.g. 'x = 5;'
 // being a valid statement
 type ExpressionStatement struct {
@@ -126,6 +207,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() lexer.Position  { return es.Token.Pos }
 
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -138,12 +220,25 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token lexer.Token
 	Value int
+	Big   *big.Int // Set instead of Value if the literal doesn't fit in an int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() lexer.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral is our object responsible for e.g. '2.5;'
+type FloatLiteral struct {
+	Token lexer.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() lexer.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 // PrefixExpression is our object responsible for e.g. '!true;'
 type PrefixExpression struct {
 	Token    lexer.Token // The prefix token e.g. '!'
@@ -153,6 +248,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() lexer.Position  { return pe.Token.Pos }
 
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -175,6 +271,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() lexer.Position  { return ie.Token.Pos }
 
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -188,6 +285,29 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression is our object responsible for e.g. 'x = 5' or 'x += 5'
+// updating an existing binding rather than creating a new one like 'let'
+type AssignExpression struct {
+	Token    lexer.Token // The assignment operator token, e.g. '=' or '+='
+	Target   Expression  // What is being assigned to, an Identifier or IndexExpression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() lexer.Position  { return ae.Token.Pos }
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+
+	return out.String()
+}
+
 type Boolean struct {
 	Token lexer.Token
 	Value bool
@@ -195,17 +315,23 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() lexer.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// IfExpression is our object responsible for e.g. 'if (x) { ... } else { ... }'
+// an 'else if' is kept as the next IfExpression in the chain in ElseIf,
+// at most one of ElseIf and Alternative is set
 type IfExpression struct {
 	Token       lexer.Token // The 'if' token
 	Condition   Expression
 	Consequence *BlockStatement
+	ElseIf      *IfExpression
 	Alternative *BlockStatement
 }
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() lexer.Position  { return ie.Token.Pos }
 
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
@@ -215,6 +341,11 @@ func (ie *IfExpression) String() string {
 	out.WriteString(" ")
 	out.WriteString(ie.Consequence.String())
 
+	if ie.ElseIf != nil {
+		out.WriteString("else ")
+		out.WriteString(ie.ElseIf.String())
+	}
+
 	if ie.Alternative != nil {
 		out.WriteString("else ")
 		out.WriteString(ie.Alternative.String())
@@ -230,6 +361,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() lexer.Position  { return bs.Token.Pos }
 
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -245,10 +377,12 @@ type FunctionLiteral struct {
 	Token      lexer.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // The name it is bound to by a let statement, if any
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() lexer.Position  { return fl.Token.Pos }
 
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
@@ -275,6 +409,7 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() lexer.Position  { return ce.Token.Pos }
 
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -299,6 +434,7 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() lexer.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 type ArrayLiteral struct {
@@ -308,6 +444,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() lexer.Position  { return al.Token.Pos }
 
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
@@ -332,6 +469,7 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() lexer.Position  { return ie.Token.Pos }
 
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -352,6 +490,7 @@ type HashLiteral struct {
 
 func (h *HashLiteral) expressionNode()      {}
 func (h *HashLiteral) TokenLiteral() string { return h.Token.Literal }
+func (h *HashLiteral) Pos() lexer.Position  { return h.Token.Pos }
 
 func (h *HashLiteral) String() string {
 	var out bytes.Buffer
@@ -4,20 +4,125 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+
+	"github.com/FollowTheProcess/monkey/lexer"
 )
 
 const (
 	OpConstant Opcode = iota
 	OpAdd
+	OpPop
+	OpTrue
+	OpFalse
+	OpNull
+	OpGreaterThan
+	OpJump
+	OpJumpNotTruthy
+	OpGetGlobal
+	OpSetGlobal
+	OpCall
+	OpReturnValue
+	OpReturn
+	OpGetLocal
+	OpSetLocal
+	OpClosure
+	OpGetFree
+	OpCurrentClosure
+	OpArray
+	OpHash
+	OpIndex
+	OpGetBuiltin
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpMinus
+	OpBang
+	OpGreaterThanOrEqual
+	OpMod
+	OpIter
+	OpIterNext
+	OpSetIndex
+	OpDupPair
+	OpLessThan
+	OpLessThanOrEqual
+	OpNewCell
+	OpGetCell
+	OpSetCell
 )
 
 var definitions = map[Opcode]*Definition{
-	OpConstant: {"OpConstant", []int{2}},
-	OpAdd:      {"OpAdd", []int{}},
+	OpConstant:      {"OpConstant", []int{2}},
+	OpAdd:           {"OpAdd", []int{}},
+	OpPop:           {"OpPop", []int{}},
+	OpTrue:          {"OpTrue", []int{}},
+	OpFalse:         {"OpFalse", []int{}},
+	OpNull:          {"OpNull", []int{}},
+	OpGreaterThan:   {"OpGreaterThan", []int{}},
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpGetGlobal:     {"OpGetGlobal", []int{2}},
+	OpSetGlobal:     {"OpSetGlobal", []int{2}},
+	OpCall:          {"OpCall", []int{1}},
+	OpReturnValue:   {"OpReturnValue", []int{}},
+	OpReturn:        {"OpReturn", []int{}},
+	OpGetLocal:      {"OpGetLocal", []int{1}},
+	OpSetLocal:      {"OpSetLocal", []int{1}},
+
+	// OpClosure operands are the constant index of the function
+	// and the number of free variables sitting on the stack
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+	OpArray: {"OpArray", []int{2}}, // Operand is the number of elements
+	OpHash:  {"OpHash", []int{2}},  // Operand is the number of keys plus values
+	OpIndex: {"OpIndex", []int{}},
+
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}}, // Operand is the index into object.Builtins
+
+	OpSub:      {"OpSub", []int{}},
+	OpMul:      {"OpMul", []int{}},
+	OpDiv:      {"OpDiv", []int{}},
+	OpEqual:    {"OpEqual", []int{}},
+	OpNotEqual: {"OpNotEqual", []int{}},
+	OpMinus:    {"OpMinus", []int{}},
+	OpBang:     {"OpBang", []int{}},
+
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	OpMod:                {"OpMod", []int{}},
+
+	// OpIter replaces the iterable on top of the stack with an iterator over it
+	// OpIterNext pushes the iterator's next element, or pops the iterator
+	// and jumps to its operand if there are no more
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{2}},
+
+	// OpSetIndex pops a value, an index and the array or hash to store it in
+	// leaving the value on the stack. OpDupPair pushes copies of the top two
+	// elements so a compound assignment like 'arr[i] += 1' can read then write
+	OpSetIndex: {"OpSetIndex", []int{}},
+	OpDupPair:  {"OpDupPair", []int{}},
+
+	OpLessThan:        {"OpLessThan", []int{}},
+	OpLessThanOrEqual: {"OpLessThanOrEqual", []int{}},
+
+	// Captured locals that are assigned to live in a cell shared with the closures
+	// capturing them. OpNewCell replaces the local at its operand with a cell
+	// holding it, OpGetCell replaces a cell with its value and OpSetCell pops a cell
+	// then stores the value under it in the cell
+	OpNewCell: {"OpNewCell", []int{1}},
+	OpGetCell: {"OpGetCell", []int{}},
+	OpSetCell: {"OpSetCell", []int{}},
 }
 
 type Instructions []byte
 
+// SourceMap maps the offset of an instruction to the position
+// of the source code it was compiled from
+type SourceMap map[int]lexer.Position
+
 func (i Instructions) String() string {
 	var out bytes.Buffer
 
@@ -51,6 +156,8 @@ func (i Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -95,6 +202,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
 		}
 		offset += width
 	}
@@ -110,6 +219,8 @@ func ReadOperands(def *Definition, instructions Instructions) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(instructions[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(instructions[offset:]))
 		}
 
 		offset += width
@@ -121,3 +232,7 @@ func ReadOperands(def *Definition, instructions Instructions) ([]int, int) {
 func ReadUint16(instructions Instructions) uint16 {
 	return binary.BigEndian.Uint16(instructions)
 }
+
+func ReadUint8(instructions Instructions) uint8 {
+	return uint8(instructions[0])
+}
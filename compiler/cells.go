@@ -0,0 +1,156 @@
+package compiler
+
+import (
+	"github.com/FollowTheProcess/monkey/ast"
+)
+
+// cellLocals returns the locals of fn that must live in a cell, parameters
+// first then the rest in the order they are defined
+//
+// Closures normally get a copy of the variables they capture, which is only
+// the same as the evaluator's shared environments if the variable never changes
+// once it is captured. So a local needs a cell if a nested function refers to it
+// and it can be written more than once: it is assigned to, defined by a for loop
+// or defined by a let that runs again in a loop or rebinds an earlier definition
+//
+// This errs on the side of cells, names are matched without working out which
+// scope they resolve to, which costs a little speed but never changes a result
+func cellLocals(fn *ast.FunctionLiteral) []string {
+	f := &cellFinder{
+		defined:  make(map[string]bool),
+		writes:   make(map[string]int),
+		captured: make(map[string]bool),
+	}
+
+	for _, p := range fn.Parameters {
+		f.define(p.Value, 1)
+	}
+
+	f.find(fn.Body, false, false)
+
+	cells := []string{}
+	for _, name := range f.locals {
+		if f.captured[name] && f.writes[name] > 1 {
+			cells = append(cells, name)
+		}
+	}
+
+	return cells
+}
+
+// cellFinder walks a function body collecting what cellLocals needs
+type cellFinder struct {
+	locals   []string        // Defined directly in the function, in order
+	defined  map[string]bool // The same names as locals, for lookups
+	writes   map[string]int  // How many times each name may be written
+	captured map[string]bool // Names referred to from nested functions
+}
+
+// define records a definition of a local that writes it n times
+func (f *cellFinder) define(name string, n int) {
+	if !f.defined[name] {
+		f.defined[name] = true
+		f.locals = append(f.locals, name)
+	}
+
+	f.writes[name] += n
+}
+
+// find walks node, loop is whether it is inside a loop of the function and
+// nested whether it is inside a function nested in it
+func (f *cellFinder) find(node ast.Node, loop, nested bool) {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			f.find(s, loop, nested)
+		}
+
+	case *ast.ExpressionStatement:
+		f.find(node.Expression, loop, nested)
+
+	case *ast.LetStatement:
+		f.find(node.Value, loop, nested)
+
+		// Lets in a nested function define its own locals not ours
+		if nested {
+			return
+		}
+
+		if loop {
+			f.define(node.Name.Value, 2)
+		} else {
+			f.define(node.Name.Value, 1)
+		}
+
+	case *ast.ReturnStatement:
+		if node.ReturnValue != nil {
+			f.find(node.ReturnValue, loop, nested)
+		}
+
+	case *ast.WhileStatement:
+		f.find(node.Condition, true, nested)
+		f.find(node.Body, true, nested)
+
+	case *ast.ForStatement:
+		f.find(node.Iterable, loop, nested)
+		if !nested {
+			f.define(node.Variable.Value, 2)
+		}
+		f.find(node.Body, true, nested)
+
+	case *ast.Identifier:
+		if nested {
+			f.captured[node.Value] = true
+		}
+
+	case *ast.AssignExpression:
+		if target, ok := node.Target.(*ast.Identifier); ok {
+			f.writes[target.Value] += 2
+		}
+		f.find(node.Target, loop, nested)
+		f.find(node.Value, loop, nested)
+
+	case *ast.PrefixExpression:
+		f.find(node.Right, loop, nested)
+
+	case *ast.InfixExpression:
+		f.find(node.Left, loop, nested)
+		f.find(node.Right, loop, nested)
+
+	case *ast.IfExpression:
+		f.find(node.Condition, loop, nested)
+		f.find(node.Consequence, loop, nested)
+
+		if node.ElseIf != nil {
+			f.find(node.ElseIf, loop, nested)
+		}
+
+		if node.Alternative != nil {
+			f.find(node.Alternative, loop, nested)
+		}
+
+	case *ast.FunctionLiteral:
+		f.find(node.Body, false, true)
+
+	case *ast.CallExpression:
+		f.find(node.Function, loop, nested)
+		for _, a := range node.Arguments {
+			f.find(a, loop, nested)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			f.find(el, loop, nested)
+		}
+
+	case *ast.HashLiteral:
+		for k, v := range node.Pairs {
+			f.find(k, loop, nested)
+			f.find(v, loop, nested)
+		}
+
+	case *ast.IndexExpression:
+		f.find(node.Left, loop, nested)
+		f.find(node.Index, loop, nested)
+	}
+}
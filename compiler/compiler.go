@@ -2,30 +2,111 @@ package compiler
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/FollowTheProcess/monkey/ast"
 	"github.com/FollowTheProcess/monkey/code"
+	"github.com/FollowTheProcess/monkey/lexer"
 	"github.com/FollowTheProcess/monkey/object"
 )
 
+// placeholderOffset is the operand emitted for jumps whose target is not yet known,
+// it is always back-patched before compilation finishes
+const placeholderOffset = 9999
+
 type ByteCode struct {
 	Instructions code.Instructions
+	SourceMap    code.SourceMap
 	Constants    []object.Object
+
+	// HasResult is whether the program ends in an expression statement, whose value
+	// is the result of the program. If it ends in a let or a loop there is no result
+	HasResult bool
+
+	// GlobalNames are the names of the globals by index, so the VM
+	// can report a global that is read before it's assigned by name
+	GlobalNames []string
+}
+
+// EmittedInstruction records an opcode and the position it was emitted at
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope holds the instructions for one function body
+// (or the top level program) while it is being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	sourceMap           code.SourceMap
+	lastInstruction     EmittedInstruction // The very last instruction emitted
+	previousInstruction EmittedInstruction // The one before lastInstruction
+	loops               []*Loop            // The loops enclosing the code being compiled, innermost last
+
+	// held is how many values the enclosing expressions have left on the stack
+	// underneath the code being compiled, waiting for an instruction after it
+	// to use them. A break or continue has to pop them before it jumps
+	held int
+}
+
+// Loop is a loop being compiled, for break and continue to jump out of
+type Loop struct {
+	start    int   // Where continue jumps to
+	breaks   []int // Positions of the jumps emitted for break, patched to the exit once it is known
+	held     int   // The held values when the body starts, anything above this is popped by break and continue
+	iterator bool  // Whether an iterator is on the stack that break must pop
 }
 
 type Compiler struct {
-	instructions code.Instructions
-	constants    []object.Object
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// Position of the node currently being compiled, recorded
+	// against every instruction emitted for it
+	pos lexer.Position
+
+	hasResult bool // See ByteCode.HasResult
 }
 
 func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
 	return &Compiler{
-		instructions: code.Instructions{},
-		constants:    []object.Object{},
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
 	}
 }
 
+// NewWithState creates a Compiler that carries on from a previous compilation,
+// reusing its symbol table and constant pool e.g. between lines in the REPL
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
 func (c *Compiler) Compile(node ast.Node) error {
+	previous := c.pos
+	c.pos = node.Pos()
+	defer func() { c.pos = previous }()
+
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
@@ -35,19 +116,32 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
+		c.hasResult = false
+		if len(node.Statements) != 0 {
+			_, c.hasResult = node.Statements[len(node.Statements)-1].(*ast.ExpressionStatement)
+		}
+
 	case *ast.ExpressionStatement:
 		err := c.Compile(node.Expression)
 		if err != nil {
 			return err
 		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
 
 	case *ast.InfixExpression:
-		err := c.Compile(node.Left)
-		if err != nil {
-			return err
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
 		}
 
-		err = c.Compile(node.Right)
+		err := c.compileHeld(node.Left, node.Right)
 		if err != nil {
 			return err
 		}
@@ -55,13 +149,335 @@ func (c *Compiler) Compile(node ast.Node) error {
 		switch node.Operator {
 		case "+":
 			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
 		default:
-			return fmt.Errorf("unknown operator: %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
+		}
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
+		}
+
+	case *ast.IfExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+
+		// Emit a jump with a bogus offset, we patch it once we know
+		// where the consequence ends
+		jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, placeholderOffset)
+
+		err = c.Compile(node.Consequence)
+		if err != nil {
+			return err
+		}
+
+		// The if expression must leave its value on the stack, a branch
+		// that is empty or ends in a statement has the value null
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
+		}
+
+		jumpPosition := c.emit(code.OpJump, placeholderOffset)
+
+		afterConsequencePosition := len(c.currentInstructions())
+		c.changeOperand(jumpNotTruthyPosition, afterConsequencePosition)
+
+		switch {
+		case node.ElseIf != nil:
+			// The rest of the chain is an if expression, so leaves its own value
+			err := c.Compile(node.ElseIf)
+			if err != nil {
+				return err
+			}
+
+		case node.Alternative == nil:
+			c.emit(code.OpNull)
+
+		default:
+			err := c.Compile(node.Alternative)
+			if err != nil {
+				return err
+			}
+
+			if c.lastInstructionIs(code.OpPop) {
+				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
+			}
+		}
+
+		afterAlternativePosition := len(c.currentInstructions())
+		c.changeOperand(jumpPosition, afterAlternativePosition)
+
+	case *ast.LetStatement:
+		// A function bound to a global or a cell refers to itself through that
+		// binding, as it may be given a new value, so it must be defined first
+		if fn, ok := node.Value.(*ast.FunctionLiteral); ok && fn.Name != "" && c.symbolTable.Rebindable(fn.Name) {
+			c.symbolTable.Define(fn.Name)
+		}
+
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		// Defined after compiling the value so 'let x = x' is an error, other
+		// functions can still call themselves through DefineFunctionName
+		symbol := c.symbolTable.Define(node.Name.Value)
+		c.storeSymbol(symbol)
+
+	case *ast.WhileStatement:
+		loopStart := len(c.currentInstructions())
+
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
 		}
 
+		exitPosition := c.emit(code.OpJumpNotTruthy, placeholderOffset)
+
+		loop := c.enterLoop(loopStart, false)
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpJump, loopStart)
+		c.changeOperand(exitPosition, len(c.currentInstructions()))
+		c.leaveLoop(loop)
+
+	case *ast.ForStatement:
+		err := c.Compile(node.Iterable)
+		if err != nil {
+			return err
+		}
+
+		// The iterator stays on the stack for the whole loop, OpIterNext
+		// takes it off again once it runs out
+		c.emit(code.OpIter)
+		c.hold(1)
+		loopStart := len(c.currentInstructions())
+		exitPosition := c.emit(code.OpIterNext, placeholderOffset)
+
+		symbol := c.symbolTable.Define(node.Variable.Value)
+		c.storeSymbol(symbol)
+
+		loop := c.enterLoop(loopStart, true)
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpJump, loopStart)
+		c.changeOperand(exitPosition, len(c.currentInstructions()))
+		c.leaveLoop(loop)
+		c.hold(-1)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: break outside of a loop", node.Pos())
+		}
+
+		c.popHeld(loop)
+		if loop.iterator {
+			c.emit(code.OpPop)
+		}
+
+		loop.breaks = append(loop.breaks, c.emit(code.OpJump, placeholderOffset))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: continue outside of a loop", node.Pos())
+		}
+
+		c.popHeld(loop)
+		c.emit(code.OpJump, loop.start)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("%s: identifier not found: %s", node.Pos(), node.Value)
+		}
+
+		c.loadSymbol(symbol)
+
+	case *ast.FunctionLiteral:
+		c.enterScope()
+
+		if node.Name != "" && !c.symbolTable.Outer.Rebindable(node.Name) {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		for _, p := range node.Parameters {
+			c.symbolTable.Define(p.Value)
+		}
+
+		// Parameters are moved into their cell, other locals
+		// start out in an empty one until their let runs
+		for _, name := range cellLocals(node) {
+			symbol := c.symbolTable.DefineCell(name)
+			c.emit(code.OpNewCell, symbol.Index)
+		}
+
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		// Implicit return of the last expression e.g. fn() { 5 }
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+
+		// Empty body or one ending in a statement returns nothing
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		localNames := c.symbolTable.Names()
+		sourceMap := c.scopes[c.scopeIndex].sourceMap
+		instructions := c.leaveScope()
+
+		// Push the captured values so OpClosure can take them off the stack,
+		// or their cells so the closure shares them
+		for _, s := range freeSymbols {
+			c.loadBinding(s)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			SourceMap:     sourceMap,
+			NumLocals:     numLocals,
+			LocalNames:    localNames,
+			NumParameters: len(node.Parameters),
+		}
+		fnIndex := c.addConstant(compiledFn)
+		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpReturnValue)
+
+	case *ast.CallExpression:
+		operands := []ast.Node{node.Function}
+		for _, a := range node.Arguments {
+			operands = append(operands, a)
+		}
+
+		err := c.compileHeld(operands...)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
 	case *ast.IntegerLiteral:
-		integer := &object.Integer{Value: node.Value}
+		integer := &object.Integer{Value: node.Value, Big: node.Big}
 		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.ArrayLiteral:
+		elements := []ast.Node{}
+		for _, el := range node.Elements {
+			elements = append(elements, el)
+		}
+
+		err := c.compileHeld(elements...)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		// Go maps are unordered so sort the keys to make
+		// the emitted instructions deterministic
+		keys := []ast.Expression{}
+		for k := range node.Pairs {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		pairs := []ast.Node{}
+		for _, k := range keys {
+			pairs = append(pairs, k, node.Pairs[k])
+		}
+
+		err := c.compileHeld(pairs...)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.compileHeld(node.Left, node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
 	}
 
 	return nil
@@ -69,8 +485,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 func (c *Compiler) ByteCode() *ByteCode {
 	return &ByteCode{
-		Instructions: c.instructions,
+		Instructions: c.currentInstructions(),
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
 		Constants:    c.constants,
+		HasResult:    c.hasResult,
+		GlobalNames:  c.symbolTable.Names(),
 	}
 }
 
@@ -82,11 +501,361 @@ func (c *Compiler) addConstant(obj object.Object) int {
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	instruction := code.Make(op, operands...)
 	position := c.addInstruction(instruction)
+
+	if c.pos.IsValid() {
+		c.scopes[c.scopeIndex].sourceMap[position] = c.pos
+	}
+
+	c.setLastInstruction(op, position)
+
 	return position
 }
 
 func (c *Compiler) addInstruction(instruction []byte) int {
-	insertionIndex := len(c.instructions)
-	c.instructions = append(c.instructions, instruction...)
+	insertionIndex := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), instruction...)
 	return insertionIndex
 }
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, position int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: position}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+// compoundOperators are the opcodes that combine the current and new values
+// in a compound assignment like 'x += 1'
+var compoundOperators = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+	"/=": code.OpDiv,
+}
+
+// compileAssignExpression stores a new value in an existing binding, or an element
+// of an array or hash, leaving the value on the stack as the result of the expression
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return c.compileIdentifierAssignment(node, target)
+
+	case *ast.IndexExpression:
+		return c.compileIndexAssignment(node, target)
+
+	default:
+		return fmt.Errorf("%s: cannot assign to %s", node.Pos(), node.Target)
+	}
+}
+
+func (c *Compiler) compileIdentifierAssignment(node *ast.AssignExpression, target *ast.Identifier) error {
+	symbol, ok := c.symbolTable.Resolve(target.Value)
+	switch {
+	case !ok, symbol.Scope == BuiltinScope:
+		return fmt.Errorf("%s: cannot assign to undefined identifier: %s", node.Pos(), target.Value)
+
+	// Captured variables that are assigned to are given a cell by cellLocals,
+	// without one the closure only has a copy of the value to update
+	case symbol.Scope == FreeScope && !symbol.Cell:
+		return fmt.Errorf("%s: cannot assign to %s, it is captured from an enclosing function", node.Pos(), target.Value)
+	}
+
+	if node.Operator != "=" {
+		c.loadSymbol(symbol)
+		c.hold(1)
+		defer c.hold(-1)
+	}
+
+	err := c.compileAssignedValue(node)
+	if err != nil {
+		return err
+	}
+
+	c.storeSymbol(symbol)
+	c.loadSymbol(symbol)
+
+	return nil
+}
+
+func (c *Compiler) compileIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression) error {
+	err := c.compileHeld(target.Left, target.Index)
+	if err != nil {
+		return err
+	}
+
+	c.hold(2)
+	defer c.hold(-2)
+
+	// Keep the array and index for OpSetIndex underneath the current value
+	if node.Operator != "=" {
+		c.emit(code.OpDupPair)
+		c.emit(code.OpIndex)
+		c.hold(1)
+		defer c.hold(-1)
+	}
+
+	err = c.compileAssignedValue(node)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpSetIndex)
+
+	return nil
+}
+
+// compileAssignedValue compiles the value an assignment stores, for a compound
+// assignment the current value must already be on the stack to combine it with
+func (c *Compiler) compileAssignedValue(node *ast.AssignExpression) error {
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	if node.Operator == "=" {
+		return nil
+	}
+
+	op, ok := compoundOperators[node.Operator]
+	if !ok {
+		return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
+	}
+
+	c.emit(op)
+
+	return nil
+}
+
+// compileLogicalExpression compiles && and || so the right hand side is jumped
+// over if the left decides the result, both leave a Boolean on the stack
+//
+//	a && b                     a || b
+//	  <a>                        <a>
+//	  OpJumpNotTruthy false      OpJumpNotTruthy right
+//	  <b>                        OpTrue
+//	  OpJumpNotTruthy false      OpJump end
+//	  OpTrue                   right:
+//	  OpJump end                 <b>
+//	false:                       OpJumpNotTruthy false
+//	  OpFalse                    OpTrue
+//	end:                         OpJump end
+//	                           false:
+//	                             OpFalse
+//	                           end:
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	leftNotTruthyPosition := c.emit(code.OpJumpNotTruthy, placeholderOffset)
+
+	jumpToEndPositions := []int{}
+	if node.Operator == "||" {
+		c.emit(code.OpTrue)
+		jumpToEndPositions = append(jumpToEndPositions, c.emit(code.OpJump, placeholderOffset))
+		c.changeOperand(leftNotTruthyPosition, len(c.currentInstructions()))
+	}
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	rightNotTruthyPosition := c.emit(code.OpJumpNotTruthy, placeholderOffset)
+	c.emit(code.OpTrue)
+	jumpToEndPositions = append(jumpToEndPositions, c.emit(code.OpJump, placeholderOffset))
+
+	falsePosition := c.emit(code.OpFalse)
+	c.changeOperand(rightNotTruthyPosition, falsePosition)
+	if node.Operator == "&&" {
+		c.changeOperand(leftNotTruthyPosition, falsePosition)
+	}
+
+	endPosition := len(c.currentInstructions())
+	for _, position := range jumpToEndPositions {
+		c.changeOperand(position, endPosition)
+	}
+
+	return nil
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// removeLastPop strips the trailing OpPop so that a block
+// leaves its final value on the stack
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+// replaceLastPopWithReturn turns the trailing OpPop of a function body
+// into an OpReturnValue so the last expression is implicitly returned
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPosition := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPosition, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+// replaceInstruction overwrites the instruction at position with newInstruction
+// the caller must make sure both are the same length
+func (c *Compiler) replaceInstruction(position int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[position+i] = newInstruction[i]
+	}
+}
+
+// changeOperand re-makes the instruction at opPosition with a new operand,
+// used to back-patch jump offsets
+func (c *Compiler) changeOperand(opPosition int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPosition])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPosition, newInstruction)
+}
+
+// enterScope starts a new compilation scope (and symbol table) for a function body
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// compileHeld compiles each node in turn, counting the value each one leaves
+// on the stack as held while the ones after it are compiled. The values are
+// no longer counted once it returns, the caller emits whatever uses them
+func (c *Compiler) compileHeld(nodes ...ast.Node) error {
+	held := c.scopes[c.scopeIndex].held
+	defer func() { c.scopes[c.scopeIndex].held = held }()
+
+	for _, node := range nodes {
+		err := c.Compile(node)
+		if err != nil {
+			return err
+		}
+
+		c.hold(1)
+	}
+
+	return nil
+}
+
+// hold records that n more values are held on the stack, or fewer if n is negative
+func (c *Compiler) hold(n int) {
+	c.scopes[c.scopeIndex].held += n
+}
+
+// popHeld emits a pop for every value held on the stack since the body of loop started
+// so that jumping out of the middle of an expression leaves the stack as the loop expects
+func (c *Compiler) popHeld(loop *Loop) {
+	for i := loop.held; i < c.scopes[c.scopeIndex].held; i++ {
+		c.emit(code.OpPop)
+	}
+}
+
+// enterLoop starts compiling a loop whose next iteration begins at start
+func (c *Compiler) enterLoop(start int, iterator bool) *Loop {
+	loop := &Loop{start: start, held: c.scopes[c.scopeIndex].held, iterator: iterator}
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, loop)
+
+	return loop
+}
+
+// leaveLoop finishes compiling a loop, pointing its breaks at the
+// instruction after it
+func (c *Compiler) leaveLoop(loop *Loop) {
+	for _, position := range loop.breaks {
+		c.changeOperand(position, len(c.currentInstructions()))
+	}
+
+	loops := c.scopes[c.scopeIndex].loops
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+}
+
+// currentLoop returns the innermost loop in the current function, or nil if there isn't one
+func (c *Compiler) currentLoop() *Loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+
+	return loops[len(loops)-1]
+}
+
+// leaveScope pops the current compilation scope, returning the instructions
+// compiled inside it
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+// storeSymbol pops the value on top of the stack into s
+// which must be a global, a local or a captured cell
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Cell {
+		c.loadBinding(s)
+		c.emit(code.OpSetCell)
+		return
+	}
+
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// loadSymbol pushes the value of s, taking it out of its cell if it has one
+func (c *Compiler) loadSymbol(s Symbol) {
+	c.loadBinding(s)
+	if s.Cell {
+		c.emit(code.OpGetCell)
+	}
+}
+
+// loadBinding pushes what is stored for s, which for a cell is the cell itself
+func (c *Compiler) loadBinding(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
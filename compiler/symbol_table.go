@@ -0,0 +1,176 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+
+	// BuiltinScope is for the functions in object.Builtins
+	BuiltinScope SymbolScope = "BUILTIN"
+
+	// FreeScope is for variables captured from an enclosing function
+	FreeScope SymbolScope = "FREE"
+	// FunctionScope is for the name of the function currently being compiled
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+// Symbol holds everything the compiler needs to know about
+// an identifier in order to resolve it
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+	Cell  bool // Whether the value lives in a cell, see DefineCell
+}
+
+// SymbolTable associates identifiers with their Symbol
+type SymbolTable struct {
+	Outer       *SymbolTable // The enclosing table, nil for the global table
+	FreeSymbols []Symbol     // The original symbols of any captured free variables
+
+	store          map[string]Symbol
+	reserved       map[string]Symbol // Cells given an index ahead of their definition
+	numDefinitions int
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	r := make(map[string]Symbol)
+	return &SymbolTable{store: s, reserved: r}
+}
+
+// NewEnclosedSymbolTable creates a SymbolTable for a new local scope
+// nested inside outer
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define creates a new Symbol for name, giving it the next free index
+// in the table's scope
+//
+// Defining a name again in the same scope reuses its index so that, like
+// in the evaluator, the new value is seen by code already compiled to use it
+// e.g. the condition of a while loop whose body rebinds the variable
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	if existing, ok := s.store[name]; ok && existing.Scope == symbol.Scope {
+		return existing
+	}
+
+	if reserved, ok := s.reserved[name]; ok {
+		delete(s.reserved, name)
+		s.store[name] = reserved
+		return reserved
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineCell makes name a local whose value lives in a cell, so closures that
+// capture it share it with this scope rather than taking a copy
+//
+// A name already defined, like a parameter, is turned into a cell in place.
+// Otherwise its index is reserved, so the cell can be created on entry to the
+// function, but the name can't be resolved until Define is called for it
+func (s *SymbolTable) DefineCell(name string) Symbol {
+	if existing, ok := s.store[name]; ok && existing.Scope == LocalScope {
+		existing.Cell = true
+		s.store[name] = existing
+		return existing
+	}
+
+	symbol := Symbol{Name: name, Scope: LocalScope, Index: s.numDefinitions, Cell: true}
+	s.reserved[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineBuiltin defines a builtin function name, index is its
+// position in object.Builtins
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName defines the name of the function whose scope this table
+// belongs to, so it can refer to itself without capturing itself as a free variable
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Rebindable reports whether defining name in s gives a binding that can take a
+// new value after a closure captures it, a global or a local that lives in a cell
+//
+// A function bound to one of these can't use DefineFunctionName to refer to
+// itself, it has to go through the binding to see any new value
+func (s *SymbolTable) Rebindable(name string) bool {
+	if s.Outer == nil {
+		return true
+	}
+
+	symbol, ok := s.store[name]
+	if !ok {
+		symbol, ok = s.reserved[name]
+	}
+
+	return ok && symbol.Scope == LocalScope && symbol.Cell
+}
+
+// Names returns the names of the globals or locals defined in s by index
+func (s *SymbolTable) Names() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+			names[symbol.Index] = name
+		}
+	}
+
+	return names
+}
+
+// Resolve looks up a previously defined Symbol by name, walking out
+// through the enclosing tables if it is not found in this one
+//
+// Locals found in an enclosing function are turned into free variables
+// of this one
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+	return obj, ok
+}
+
+// defineFree records original as a free variable of this scope and returns
+// the FreeScope symbol that now refers to it
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope, Cell: original.Cell}
+	s.store[original.Name] = symbol
+	return symbol
+}
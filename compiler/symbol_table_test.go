@@ -0,0 +1,308 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+		"d": {Name: "d", Scope: LocalScope, Index: 1},
+		"e": {Name: "e", Scope: LocalScope, Index: 0},
+		"f": {Name: "f", Scope: LocalScope, Index: 1},
+	}
+
+	global := NewSymbolTable()
+
+	a := global.Define("a")
+	if a != expected["a"] {
+		t.Errorf("wrong symbol for a: got %+v, wanted %+v", a, expected["a"])
+	}
+
+	b := global.Define("b")
+	if b != expected["b"] {
+		t.Errorf("wrong symbol for b: got %+v, wanted %+v", b, expected["b"])
+	}
+
+	firstLocal := NewEnclosedSymbolTable(global)
+
+	for _, name := range []string{"c", "d"} {
+		sym := firstLocal.Define(name)
+		if sym != expected[name] {
+			t.Errorf("wrong symbol for %s: got %+v, wanted %+v", name, sym, expected[name])
+		}
+	}
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+
+	for _, name := range []string{"e", "f"} {
+		sym := secondLocal.Define(name)
+		if sym != expected[name] {
+			t.Errorf("wrong symbol for %s: got %+v, wanted %+v", name, sym, expected[name])
+		}
+	}
+}
+
+func TestResolveGlobal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+	}
+
+	for _, sym := range expected {
+		result, ok := global.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+
+		if result != sym {
+			t.Errorf("wrong symbol for %s: got %+v, wanted %+v", sym.Name, result, sym)
+		}
+	}
+}
+
+func TestResolveNestedLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+	firstLocal.Define("d")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.Define("f")
+
+	tests := []struct {
+		table    *SymbolTable
+		expected []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: GlobalScope, Index: 1},
+				{Name: "c", Scope: LocalScope, Index: 0},
+				{Name: "d", Scope: LocalScope, Index: 1},
+			},
+		},
+		{
+			secondLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: GlobalScope, Index: 1},
+				{Name: "c", Scope: FreeScope, Index: 0},
+				{Name: "d", Scope: FreeScope, Index: 1},
+				{Name: "e", Scope: LocalScope, Index: 0},
+				{Name: "f", Scope: LocalScope, Index: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, sym := range tt.expected {
+			result, ok := tt.table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+
+			if result != sym {
+				t.Errorf("wrong symbol for %s: got %+v, wanted %+v", sym.Name, result, sym)
+			}
+		}
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "c", Scope: FreeScope, Index: 0},
+		{Name: "e", Scope: LocalScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := secondLocal.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+
+		if result != sym {
+			t.Errorf("wrong symbol for %s: got %+v, wanted %+v", sym.Name, result, sym)
+		}
+	}
+
+	wantFree := []Symbol{{Name: "c", Scope: LocalScope, Index: 0}}
+	if len(secondLocal.FreeSymbols) != len(wantFree) {
+		t.Fatalf("wrong number of free symbols: got %d, wanted %d", len(secondLocal.FreeSymbols), len(wantFree))
+	}
+
+	for i, sym := range wantFree {
+		if secondLocal.FreeSymbols[i] != sym {
+			t.Errorf("wrong free symbol: got %+v, wanted %+v", secondLocal.FreeSymbols[i], sym)
+		}
+	}
+
+	if _, ok := secondLocal.Resolve("unknown"); ok {
+		t.Errorf("name unknown resolved, but was never defined")
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("wrong symbol for %s: got %+v, wanted %+v", expected.Name, result, expected)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	global.Define("a")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("wrong symbol for %s: got %+v, wanted %+v", expected.Name, result, expected)
+	}
+}
+
+func TestDefineResolveBuiltins(t *testing.T) {
+	global := NewSymbolTable()
+	firstLocal := NewEnclosedSymbolTable(global)
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+
+	expected := []Symbol{
+		{Name: "a", Scope: BuiltinScope, Index: 0},
+		{Name: "c", Scope: BuiltinScope, Index: 1},
+		{Name: "e", Scope: BuiltinScope, Index: 2},
+		{Name: "f", Scope: BuiltinScope, Index: 3},
+	}
+
+	for i, v := range expected {
+		global.DefineBuiltin(i, v.Name)
+	}
+
+	for _, table := range []*SymbolTable{global, firstLocal, secondLocal} {
+		for _, sym := range expected {
+			result, ok := table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+
+			if result != sym {
+				t.Errorf("wrong symbol for %s: got %+v, wanted %+v", sym.Name, result, sym)
+			}
+		}
+	}
+}
+
+func TestRedefine(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	if a := global.Define("a"); a != (Symbol{Name: "a", Scope: GlobalScope, Index: 0}) {
+		t.Errorf("redefining a global did not reuse its index: got %+v", a)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+
+	// Shadowing a global makes a new local rather than reusing the global
+	if a := local.Define("a"); a != (Symbol{Name: "a", Scope: LocalScope, Index: 0}) {
+		t.Errorf("wrong symbol for shadowed a: got %+v", a)
+	}
+
+	if a := local.Define("a"); a != (Symbol{Name: "a", Scope: LocalScope, Index: 0}) {
+		t.Errorf("redefining a local did not reuse its index: got %+v", a)
+	}
+
+	if local.numDefinitions != 1 {
+		t.Errorf("wrong number of definitions: got %d, wanted %d", local.numDefinitions, 1)
+	}
+}
+
+func TestDefineCell(t *testing.T) {
+	global := NewSymbolTable()
+	local := NewEnclosedSymbolTable(global)
+	local.Define("a")
+
+	// An existing local becomes a cell in place
+	if a := local.DefineCell("a"); a != (Symbol{Name: "a", Scope: LocalScope, Index: 0, Cell: true}) {
+		t.Errorf("wrong symbol for cell a: got %+v", a)
+	}
+
+	// Anything else has its index reserved but can't be resolved until it's defined
+	local.DefineCell("b")
+	if _, ok := local.Resolve("b"); ok {
+		t.Errorf("reserved cell b resolved before it was defined")
+	}
+
+	local.Define("c")
+
+	want := Symbol{Name: "b", Scope: LocalScope, Index: 1, Cell: true}
+	if b := local.Define("b"); b != want {
+		t.Errorf("defining b did not use its reserved cell: got %+v, wanted %+v", b, want)
+	}
+
+	// Closures capturing a cell know it is one
+	nested := NewEnclosedSymbolTable(local)
+	want = Symbol{Name: "b", Scope: FreeScope, Index: 0, Cell: true}
+	if b, _ := nested.Resolve("b"); b != want {
+		t.Errorf("wrong symbol for captured cell b: got %+v, wanted %+v", b, want)
+	}
+}
+
+func TestRebindable(t *testing.T) {
+	global := NewSymbolTable()
+	if !global.Rebindable("a") {
+		t.Errorf("globals should always be rebindable")
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("a")
+	local.DefineCell("b")
+
+	if local.Rebindable("a") {
+		t.Errorf("a plain local should not be rebindable")
+	}
+
+	if !local.Rebindable("b") {
+		t.Errorf("a reserved cell should be rebindable")
+	}
+
+	if local.Rebindable("c") {
+		t.Errorf("an undefined local should not be rebindable")
+	}
+}
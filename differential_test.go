@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FollowTheProcess/monkey/ast"
+	"github.com/FollowTheProcess/monkey/compiler"
+	"github.com/FollowTheProcess/monkey/eval"
+	"github.com/FollowTheProcess/monkey/lexer"
+	"github.com/FollowTheProcess/monkey/object"
+	"github.com/FollowTheProcess/monkey/parser"
+	"github.com/FollowTheProcess/monkey/vm"
+)
+
+// TestEvalVMAgree runs every program in testdata through both the evaluator
+// and the compiler + VM, checking each against the program's .want file
+// and against each other
+//
+// Errors from either backend are rendered the way the evaluator
+// inspects them i.e. "ERROR: <message>"
+func TestEvalVMAgree(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "*.monkey"))
+	if err != nil {
+		t.Fatalf("could not glob testdata: %s", err)
+	}
+
+	if len(programs) == 0 {
+		t.Fatal("no programs found in testdata")
+	}
+
+	for _, path := range programs {
+		name := filepath.Base(path)
+
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("could not read program: %s", err)
+			}
+
+			wantFile := strings.TrimSuffix(path, ".monkey") + ".want"
+			want, err := os.ReadFile(wantFile)
+			if err != nil {
+				t.Fatalf("could not read expected output: %s", err)
+			}
+
+			program := parseProgram(t, name, string(source))
+
+			evalGot := runEval(program)
+			vmGot := runVM(program)
+			wantGot := strings.TrimSpace(string(want))
+
+			if evalGot != vmGot {
+				t.Errorf("%s: eval and vm disagree\n%s", name, diff("eval", evalGot, "vm", vmGot))
+			}
+
+			if evalGot != wantGot {
+				t.Errorf("%s: wrong eval output\n%s", name, diff("want", wantGot, "eval", evalGot))
+			}
+
+			if vmGot != wantGot {
+				t.Errorf("%s: wrong vm output\n%s", name, diff("want", wantGot, "vm", vmGot))
+			}
+		})
+	}
+}
+
+func parseProgram(t *testing.T, name, source string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.NewWithFilename(name, source))
+	program := p.ParseProgram()
+
+	if err := p.Errors().Err(); err != nil {
+		t.Fatalf("%s: parser errors:\n%s", name, err)
+	}
+
+	return program
+}
+
+func runEval(program *ast.Program) string {
+	result := eval.Eval(program, object.NewEnvironment())
+	if result == nil {
+		return ""
+	}
+
+	return result.Inspect()
+}
+
+func runVM(program *ast.Program) string {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return (&object.Error{Message: err.Error()}).Inspect()
+	}
+
+	machine := vm.New(comp.ByteCode())
+	if err := machine.Run(); err != nil {
+		return (&object.Error{Message: err.Error()}).Inspect()
+	}
+
+	result := machine.LastPoppedStackElem()
+	if result == nil {
+		return ""
+	}
+
+	return result.Inspect()
+}
+
+// diff renders two outputs line by line, marking the lines that differ
+func diff(aName, a, bName, b string) string {
+	aLines := strings.Split(a, "\n")
+	bLines := strings.Split(b, "\n")
+
+	n := len(aLines)
+	if len(bLines) > n {
+		n = len(bLines)
+	}
+
+	width := len(aName)
+	if len(bName) > width {
+		width = len(bName)
+	}
+
+	var out strings.Builder
+
+	for i := 0; i < n; i++ {
+		var aLine, bLine string
+		if i < len(aLines) {
+			aLine = aLines[i]
+		}
+		if i < len(bLines) {
+			bLine = bLines[i]
+		}
+
+		if aLine == bLine {
+			out.WriteString("  " + strings.Repeat(" ", width) + "  " + aLine + "\n")
+			continue
+		}
+
+		out.WriteString("- " + aName + strings.Repeat(" ", width-len(aName)) + ": " + aLine + "\n")
+		out.WriteString("+ " + bName + strings.Repeat(" ", width-len(bName)) + ": " + bLine + "\n")
+	}
+
+	return out.String()
+}
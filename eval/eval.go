@@ -2,18 +2,26 @@ package eval
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/FollowTheProcess/monkey/ast"
 	"github.com/FollowTheProcess/monkey/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return withPosition(eval(node, env), node)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -22,28 +30,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return Eval(node.Expression, env)
 
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return &object.Integer{Value: node.Value, Big: node.Big}
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 
 	case *ast.Boolean:
 		return nativeBooltoBooleanObject(node.Value)
 
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env.CheckedArithmetic())
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
@@ -51,16 +69,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.Return{Value: val}
 
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
@@ -75,11 +105,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isAbrupt(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isAbrupt(args[0]) {
 			return args[0]
 		}
 
@@ -90,19 +120,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isAbrupt(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isAbrupt(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
@@ -138,17 +168,24 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN || rt == object.ERROR {
-				return result
-			}
+		if isAbrupt(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
+// withPosition stamps an error that doesn't yet know where it came from
+// with the position of node, as errors bubble up the innermost node wins
+func withPosition(obj object.Object, node ast.Node) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && !errObj.Pos.IsValid() {
+		errObj.Pos = node.Pos()
+	}
+
+	return obj
+}
+
 func nativeBooltoBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -184,18 +221,26 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER {
+	switch right := right.(type) {
+	case *object.Integer:
+		return object.NegateInteger(right)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+// evalInfixExpression applies operator to left and right, if checked an integer
+// result that doesn't fit in an int is an error rather than becoming a big integer
+func evalInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
 	switch {
 	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, checked)
+
+	// Any other mix of numbers is done in floating point
+	case object.IsNumber(left) && object.IsNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 
 	case left.Type() == object.STRING && right.Type() == object.STRING:
 		return evalStringInfixExpression(operator, left, right)
@@ -214,6 +259,133 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+// evalAssignExpression updates an existing binding, or an element of an array
+// or hash. For a compound assignment like 'x += 1' the new value is worked
+// out from the current one
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalIdentifierAssignment(node, target, env)
+
+	case *ast.IndexExpression:
+		return evalIndexAssignment(node, target, env)
+
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+func evalIdentifierAssignment(node *ast.AssignExpression, target *ast.Identifier, env *object.Environment) object.Object {
+	current, ok := env.Get(target.Value)
+	if !ok {
+		return newError("cannot assign to undefined identifier: %s", target.Value)
+	}
+
+	value := evalAssignedValue(node, current, env)
+	if isAbrupt(value) {
+		return value
+	}
+
+	env.Assign(target.Value, value)
+
+	return value
+}
+
+func evalIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	var current object.Object
+	if node.Operator != "=" {
+		current = evalIndexExpression(left, index)
+		if isAbrupt(current) {
+			return current
+		}
+	}
+
+	value := evalAssignedValue(node, current, env)
+	if isAbrupt(value) {
+		return value
+	}
+
+	return evalSetIndex(left, index, value)
+}
+
+// evalAssignedValue evaluates the value an assignment stores, for a compound
+// assignment that is the operator applied to the current value and the new one
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isAbrupt(value) || node.Operator == "=" {
+		return value
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+
+	return evalInfixExpression(operator, current, value, env.CheckedArithmetic())
+}
+
+// evalSetIndex stores value in an array or hash, changing it in place
+func evalSetIndex(left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+
+		if integer.IsBig() || integer.Value < 0 || integer.Value >= len(left.Elements) {
+			return newError("index out of range: %s with length %d", integer.Inspect(), len(left.Elements))
+		}
+
+		left.Elements[integer.Value] = value
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("object %s is not hashable", index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
+
+// evalLogicalExpression evaluates && and || which short-circuit, so the right hand
+// side is only evaluated if the left doesn't already decide the result
+// The result is always a Boolean, based on the truthiness of the operands
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+
+	return nativeBooltoBooleanObject(isTruthy(right))
+}
+
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
@@ -226,20 +398,81 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 
+	var result object.Object
 	switch {
 	case isTruthy(condition):
-		return Eval(ie.Consequence, env)
+		result = Eval(ie.Consequence, env)
+
+	case ie.ElseIf != nil:
+		result = Eval(ie.ElseIf, env)
 
 	case ie.Alternative != nil:
-		return Eval(ie.Alternative, env)
+		result = Eval(ie.Alternative, env)
+	}
 
-	default:
+	// A branch that is empty or ends in a statement has no value
+	if result == nil {
 		return NULL
 	}
+
+	return result
+}
+
+// evalWhileStatement runs the body for as long as the condition is truthy
+// loops are statements so there is no value unless the body returns or errors
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		result := Eval(ws.Body, env)
+		if isReturnOrError(result) {
+			return result
+		}
+
+		if result == BREAK {
+			return nil
+		}
+	}
+}
+
+// evalForStatement runs the body once for each element of the iterable
+// with the loop variable bound to it in the current environment
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	elements, ok := object.Iterate(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for _, element := range elements {
+		env.Set(fs.Variable.Value, element)
+
+		result := Eval(fs.Body, env)
+		if isReturnOrError(result) {
+			return result
+		}
+
+		if result == BREAK {
+			return nil
+		}
+	}
+
+	return nil
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
@@ -247,7 +480,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 
 	for keyNode, valueNode := range node.Pairs {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isAbrupt(key) {
 			return key
 		}
 
@@ -257,7 +490,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		}
 
 		value := Eval(valueNode, env)
-		if isError(value) {
+		if isAbrupt(value) {
 			return value
 		}
 
@@ -301,7 +534,7 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	idx := index.(*object.Integer).Value
 	max := len(arrayObject.Elements) - 1
 
-	if idx < 0 || idx > max {
+	if index.(*object.Integer).IsBig() || idx < 0 || idx > max {
 		return NULL
 	}
 
@@ -313,7 +546,7 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -325,26 +558,43 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+
 		evaluated := Eval(fn.Body, extendedEnv)
+		if evaluated == nil {
+			// The body is empty or ends in a statement
+			return NULL
+		}
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if result := fn.Fn(args...); result != nil {
+			return result
+		}
+		return NULL
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// extendFunctionEnv binds the arguments of a call to fn's parameters in a new environment
+// enclosed by the one fn was defined in, it is an error to pass the wrong number
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
+	if len(args) != len(fn.Parameters) {
+		return nil, newError("wrong number of arguments: got %d, wanted %d", len(args), len(fn.Parameters))
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for i, param := range fn.Parameters {
 		env.Set(param.Value, args[i])
 	}
 
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -360,29 +610,78 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
 		return builtin
 	}
 
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+func evalIntegerInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
+	leftInt := left.(*object.Integer)
+	rightInt := right.(*object.Integer)
+
+	switch operator {
+	case "+", "-", "*", "/", "%":
+		if operator == "/" && rightInt.IsZero() {
+			return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+		}
+
+		if operator == "%" && rightInt.IsZero() {
+			return newError("modulo by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+
+		result, overflow := object.IntegerArithmetic(operator, leftInt, rightInt)
+		if checked && overflow {
+			return newError("integer overflow: %s %s %s", left.Inspect(), operator, right.Inspect())
+		}
+		return result
+
+	case "<":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) < 0)
+
+	case ">":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) > 0)
+
+	case "<=":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) <= 0)
+
+	case ">=":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) >= 0)
+
+	case "==":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) == 0)
+
+	case "!=":
+		return nativeBooltoBooleanObject(object.CompareIntegers(leftInt, rightInt) != 0)
+
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := object.ToFloat(left)
+	rightVal := object.ToFloat(right)
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
 
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
+
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 
 	case "<":
 		return nativeBooltoBooleanObject(leftVal < rightVal)
@@ -390,6 +689,12 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case ">":
 		return nativeBooltoBooleanObject(leftVal > rightVal)
 
+	case "<=":
+		return nativeBooltoBooleanObject(leftVal <= rightVal)
+
+	case ">=":
+		return nativeBooltoBooleanObject(leftVal >= rightVal)
+
 	case "==":
 		return nativeBooltoBooleanObject(leftVal == rightVal)
 
@@ -418,10 +723,23 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
-func isError(obj object.Object) bool {
-	if obj != nil {
-		return obj.Type() == object.ERROR
+// isAbrupt reports whether obj is an error, or a return, break or continue, which
+// stop the evaluation of whatever they are found in and are passed up until
+// something handles them, so they never end up as values in data or operands
+func isAbrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
 	}
 
-	return false
+	switch obj.Type() {
+	case object.ERROR, object.RETURN, object.BREAK, object.CONTINUE:
+		return true
+	default:
+		return false
+	}
+}
+
+// isReturnOrError reports whether obj must be passed up past any loops it is in
+func isReturnOrError(obj object.Object) bool {
+	return obj != nil && (obj.Type() == object.RETURN || obj.Type() == object.ERROR)
 }
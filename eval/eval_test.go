@@ -28,6 +28,9 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +39,58 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"1180591620717411303424", "1180591620717411303424"},
+		{"1099511627776 * 1073741824", "1180591620717411303424"},
+		{"1180591620717411303424 / 1073741824", "1099511627776"},
+		{"1180591620717411303425 % 1024", "1"},
+		{"-1180591620717411303424", "-1180591620717411303424"},
+		{"1180591620717411303424 - 1180591620717411303423", "1"},
+		{"1180591620717411303424 > 9223372036854775807", "true"},
+		{"1180591620717411303424 == 1099511627776 * 1073741824", "true"},
+		{"1180591620717411303424 * 0.5", "5.902958103587057e+20"},
+		{"let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } }; factorial(25)", "15511210043330985984000000"},
+		{`{1180591620717411303424: "x"}[1099511627776 * 1073741824]`, "x"},
+		{"[1, 2][1180591620717411303424]", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.want {
+			t.Errorf("%s: got %s, wanted %s", tt.input, evaluated.Inspect(), tt.want)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"5 - 0.5", 4.5},
+		{"2 * 1.25", 2.5},
+		{"1 / 4.0", 0.25},
+		{"(1.5 + 2) * 2", 7.0},
+		{"7.5 % 2", 1.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.want)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -60,6 +115,44 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"3 >= 2", true},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"0.1 + 0.2 == 0.3", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.want)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"true || false", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && \"a\"", true},
+		{"if (false) { 1 } || 0", true},
+		// The right hand side is never evaluated, or it would be an error
+		{"false && (1 + true)", false},
+		{"true || (1 + true)", true},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +192,11 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else if (true) { 20 } else { 30 }", 20},
+		{"if (false) { 10 } else if (false) { 20 } else { 30 }", 30},
+		{"if (true) { 10 } else if (true) { 20 } else { 30 }", 10},
+		{"if (false) { 10 } else if (false) { 20 }", nil},
+		{"let x = 3; if (x == 1) { 1 } else if (x == 2) { 2 } else if (x == 3) { 3 } else { 4 }", 3},
 	}
 
 	for _, tt := range tests {
@@ -155,6 +253,42 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"true && (1 + true)",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"(1 + true) || true",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"1 / 0",
+			"division by zero: 1 / 0",
+		},
+		{
+			"let zero = fn() { 0 }; 10 / zero()",
+			"division by zero: 10 / 0",
+		},
+		{
+			"5 % 0",
+			"modulo by zero: 5 % 0",
+		},
+		{
+			"5.5 % 0",
+			"modulo by zero: 5.5 % 0",
+		},
+		{
+			"true >= false",
+			"unknown operator: BOOLEAN >= BOOLEAN",
+		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			`"a" * 2.0`,
+			"type mismatch: STRING * FLOAT",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -190,6 +324,66 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"object FUNCTION is not hashable",
 		},
+		{
+			"for (x in 5) { x }",
+			"cannot iterate over INTEGER",
+		},
+		{
+			"x = 5",
+			"cannot assign to undefined identifier: x",
+		},
+		{
+			"len += 1",
+			"cannot assign to undefined identifier: len",
+		},
+		{
+			"let x = 1; x += true",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"let x = 1; x /= 0",
+			"division by zero: 1 / 0",
+		},
+		{
+			"let arr = [1, 2, 3]; arr[3] = 4",
+			"index out of range: 3 with length 3",
+		},
+		{
+			"let arr = [1, 2, 3]; arr[-1] = 4",
+			"index out of range: -1 with length 3",
+		},
+		{
+			`let arr = [1]; arr["a"] = 4`,
+			"array index must be INTEGER, got STRING",
+		},
+		{
+			"let h = {}; h[fn(x) { x }] = 1",
+			"object FUNCTION is not hashable",
+		},
+		{
+			`let s = "abc"; s[0] = "x"`,
+			"index assignment not supported: STRING",
+		},
+		{
+			`let h = {}; h["missing"] += 1`,
+			"type mismatch: NULL + INTEGER",
+		},
+		{
+			"while (1 + true) { 1 }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"for (x in [1, 2]) { x + true }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"fn(a, b) { a + b }(1)",
+			"wrong number of arguments: got 1, wanted 2",
+		},
+		{
+			"fn() { 1 }(1, 2)",
+			"wrong number of arguments: got 2, wanted 0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -206,6 +400,34 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"5 + true;", "ERROR: test.monkey:1:3: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = -true;", "ERROR: test.monkey:2:9: unknown operator: -BOOLEAN"},
+		{"let f = fn(x) {\n  x + true\n};\nf(1);", "ERROR: test.monkey:2:5: type mismatch: INTEGER + BOOLEAN"},
+		{"len(1)", "ERROR: test.monkey:1:4: argument to `len` not supported: INTEGER"},
+		{"\n\n  foobar", "ERROR: test.monkey:3:3: identifier not found: foobar"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.NewWithFilename("test.monkey", tt.input))
+		program := p.ParseProgram()
+		evaluated := Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned: got %T (%+v)", evaluated, evaluated)
+		}
+
+		if errObj.Inspect() != tt.want {
+			t.Errorf("wrong error: got %q, wanted %q", errObj.Inspect(), tt.want)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input string
@@ -314,12 +536,21 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("hello world")`, 11},
 		{`len(1)`, "argument to `len` not supported: INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments: got 2, wanted 1"},
+		{`len([1, 2, 3])`, 3},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`rest([])`, nil},
+		{`first(1)`, "argument to `first` must be an ARRAY, got INTEGER"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 
 		switch want := tt.want.(type) {
+		case nil:
+			testNullObject(t, evaluated)
 		case int:
 			testIntegerObject(t, evaluated, want)
 		case string:
@@ -496,6 +727,141 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 2", 2},
+		{"let x = 1; let y = 0; y = x = 5; x + y", 10},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let i = 0; while (i < 10) { i += 1; }; i", 10},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; }; sum", 6},
+		// Assigning inside a function updates the binding it was defined with
+		{"let x = 1; let set = fn() { x = 2; }; set(); x", 2},
+		{"let x = 1; let f = fn() { let x = 5; x += 1; x }; f() + x", 7},
+		{"let f = fn(n) { n *= 2; n }; f(21)", 42},
+		{"let x = 1.5; x *= 2; x == 3.0", true},
+		{"let arr = [1, 2, 3]; arr[0] = 10; arr[0] + arr[2]", 13},
+		{"let arr = [1, 2, 3]; arr[1] = 5", 5},
+		{"let arr = [1, 2, 3]; arr[2] += 4; arr[2]", 7},
+		{"let arr = [[1, 2], [3, 4]]; arr[1][0] *= 10; arr[1][0]", 30},
+		{`let h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{`let h = {}; h["new"] = 3; h["new"]`, 3},
+		{`let h = {"n": 1}; h["n"] -= 5; h["n"]`, -4},
+		{`let h = {}; h[true] = 1; h[2] = 2; h[true] + h[2]`, 3},
+		// Arrays and hashes are changed in place, so every binding sees the change
+		{"let a = [1]; let b = a; b[0] = 2; a[0]", 2},
+		{"let set = fn(arr) { arr[0] = 9; }; let a = [1]; set(a); a[0]", 9},
+		{`let h = {"k": 0}; let inc = fn() { h["k"] += 1; }; inc(); inc(); h["k"]`, 2},
+		{"let arr = [0, 0, 0]; for (i in [0, 1, 2]) { arr[i] = i * i; }; arr[2]", 4},
+		{"let i = 0; let arr = [5, 6]; arr[i] += 1; i", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { let i = i + 1; }; i", 10},
+		{"let i = 0; while (false) { let i = i + 1; }; i", 0},
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; }; sum", 6},
+		{"let n = 0; for (x in []) { let n = n + 1; }; n", 0},
+		{`let n = 0; for (c in "héllo") { let n = n + 1; }; n`, 5},
+		{`let sum = 0; for (k in {1: 10, 2: 20}) { let sum = sum + k; }; sum`, 3},
+		{"let first = fn(arr) { for (x in arr) { if (x > 1) { return x; } }; -1 }; first([1, 5, 7])", 5},
+		{"let f = fn() { while (true) { return 3; } }; f()", 3},
+		{"let f = fn() { for (x in [1]) { x } }; f()", nil},
+		{"if (true) { while (false) {} }", nil},
+		// Long loops must not use up the Go stack like recursion does
+		{"let i = 0; while (i < 100000) { let i = i + 1; }; i", 100000},
+		// break and continue in the middle of an expression abandon it
+		{"let r = 0; for (x in [1, 2]) { r = 1 + if (true) { continue; } else { 2 }; }; r", 0},
+		{"let r = 0; for (x in [1, 2, 3]) { let y = [1, if (x == 2) { continue; } else { x }]; r += y[1]; }; r", 4},
+		{"let r = 0; while (true) { r = len(if (true) { break; }); }; r", 0},
+		{"let n = 0; while (n < 5000) { n += 1; let y = 1 + if (true) { continue; } else { 2 }; }; n", 5000},
+		{"let f = fn() { 1 + if (true) { return 2; } else { 3 } }; f()", 2},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue; }; let sum = sum + x; }; sum", 4},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; }; let sum = sum + x; }; sum", 3},
+		{"let i = 0; let n = 0; while (i < 10) { let i = i + 1; if (i > 3) { continue; }; let n = n + 1; }; n", 3},
+		{"let n = 0; for (x in [1, 2]) { for (y in [1, 2, 3]) { if (y == 2) { break; }; let n = n + 1; }; }; n", 2},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { break; } }; 10 }; f()", 10},
+		{"let f = fn(arr) { let n = 0; for (x in arr) { if (x == 2) { continue; }; let n = n + x; }; n }; f([1, 2, 3])", 4},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, integer)
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashIterationOrder(t *testing.T) {
+	input := `let keys = []; for (k in {"b": 1, 2: 2, "a": 3, 1: 4, true: 5}) { let keys = push(keys, k); }; keys`
+
+	evaluated := testEval(input)
+	if evaluated.Inspect() != `[true, 1, 2, a, b]` {
+		t.Errorf("wrong iteration order: got %s, wanted %s", evaluated.Inspect(), `[true, 1, 2, a, b]`)
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		// Function bodies get an enclosed environment, which must still be checked
+		{"let double = fn(x) { x * 2 }; double(4611686018427387904)", "integer overflow: 4611686018427387904 * 2"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		env := object.NewEnvironment()
+		env.SetCheckedArithmetic(true)
+		evaluated := Eval(program, env)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned: got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.want {
+			t.Errorf("wrong error message: got %s, wanted %s", errObj.Message, tt.want)
+		}
+
+		// Unchecked it should promote to a big integer
+		promoted, ok := Eval(program, object.NewEnvironment()).(*object.Integer)
+		if !ok || !promoted.IsBig() {
+			t.Errorf("%s: unchecked arithmetic should promote to a big integer, got %+v", tt.input, promoted)
+		}
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -529,6 +895,21 @@ func testIntegerObject(t *testing.T, obj object.Object, want int) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, want float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not a Float, got %T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != want {
+		t.Errorf("object has wrong value, got %g, wanted %g", result.Value, want)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
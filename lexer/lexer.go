@@ -2,7 +2,11 @@
 package lexer
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/exp/utf8string"
 )
@@ -16,21 +20,52 @@ type Lexer struct {
 	position     int                // Current position in input (points to current char)
 	readPosition int                // Current reading position (points to next char)
 	ch           rune               // Current char under examination
+
+	filename string // Name of the source, used in token positions
+	line     int    // Line of the current char
+	column   int    // Column of the current char
+	offset   int    // Byte offset of the current char
+
+	emitComments bool // Whether comments are emitted as COMMENT tokens or skipped
 }
 
 // New constructs and returns a new Lexer and initialises
 // it by reading the first character
 func New(input string) *Lexer {
-	l := &Lexer{input: utf8string.NewString(input)}
+	return NewWithFilename("", input)
+}
+
+// NewWithFilename is like New but records filename
+// in the position of every token
+func NewWithFilename(filename, input string) *Lexer {
+	l := &Lexer{input: utf8string.NewString(input), filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// EmitComments controls whether the lexer emits a COMMENT token for each comment
+// it sees, so a formatter or doc tool can keep them. By default comments are skipped
+func (l *Lexer) EmitComments(emit bool) {
+	l.emitComments = emit
+}
+
 // readChar reads the next character in the input stream
 // and advances our position markers
 // If we have not read anything or we are at the end of the input
 // it will set the current character to 0 (ASCII "NUL")
 func (l *Lexer) readChar() {
+	// Move the line, column and offset on past the char we're leaving
+	// the very first call has no previous char
+	if l.readPosition > 0 && l.ch != 0 {
+		l.offset += utf8.RuneLen(l.ch)
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
 	if l.readPosition >= l.input.RuneCount() {
 		// Nothing to do, set to 0
 		l.ch = 0
@@ -63,6 +98,8 @@ func (l *Lexer) NextToken() Token {
 	var token Token
 	l.skipWhiteSpace()
 
+	pos := l.currentPosition()
+
 	switch l.ch {
 	case '=':
 		// Look ahead to see if we have a '=='
@@ -75,9 +112,19 @@ func (l *Lexer) NextToken() Token {
 			token = newToken(ASSIGN, l.ch)
 		}
 	case '+':
-		token = newToken(PLUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			token = Token{Type: PLUSASSIGN, Literal: "+="}
+		} else {
+			token = newToken(PLUS, l.ch)
+		}
 	case '-':
-		token = newToken(MINUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			token = Token{Type: MINUSASSIGN, Literal: "-="}
+		} else {
+			token = newToken(MINUS, l.ch)
+		}
 	case '!':
 		// Look ahead to see if we have a '!='
 		if l.peekChar() == '=' {
@@ -88,14 +135,60 @@ func (l *Lexer) NextToken() Token {
 			// If not, must just be a normal '!'
 			token = newToken(BANG, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			token = Token{Type: AND, Literal: "&&"}
+		} else {
+			token = Token{Type: ILLEGAL, Literal: "unexpected character '&', did you mean '&&'?"}
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			token = Token{Type: OR, Literal: "||"}
+		} else {
+			token = Token{Type: ILLEGAL, Literal: "unexpected character '|', did you mean '||'?"}
+		}
 	case '*':
-		token = newToken(ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			token = Token{Type: ASTERISKASSIGN, Literal: "*="}
+		} else {
+			token = newToken(ASTERISK, l.ch)
+		}
 	case '/':
-		token = newToken(SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			token = Token{Type: COMMENT, Literal: l.readLineComment()}
+		case '*':
+			literal, ok := l.readBlockComment()
+			if ok {
+				token = Token{Type: COMMENT, Literal: literal}
+			} else {
+				token = Token{Type: ILLEGAL, Literal: "unterminated block comment"}
+			}
+		case '=':
+			l.readChar()
+			token = Token{Type: SLASHASSIGN, Literal: "/="}
+		default:
+			token = newToken(SLASH, l.ch)
+		}
 	case '<':
-		token = newToken(LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			token = Token{Type: LTEQ, Literal: "<="}
+		} else {
+			token = newToken(LT, l.ch)
+		}
 	case '>':
-		token = newToken(GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			token = Token{Type: GTEQ, Literal: ">="}
+		} else {
+			token = newToken(GT, l.ch)
+		}
+	case '%':
+		token = newToken(PERCENT, l.ch)
 	case ';':
 		token = newToken(SEMICOLON, l.ch)
 	case ',':
@@ -113,8 +206,19 @@ func (l *Lexer) NextToken() Token {
 	case ']':
 		token = newToken(RBRACKET, l.ch)
 	case '"':
-		token.Type = STRING
-		token.Literal = l.readString()
+		value, err := l.readString()
+		if err != "" {
+			token = Token{Type: ILLEGAL, Literal: err}
+		} else {
+			token = Token{Type: STRING, Literal: value}
+		}
+	case '`':
+		value, ok := l.readRawString()
+		if ok {
+			token = Token{Type: STRING, Literal: value}
+		} else {
+			token = Token{Type: ILLEGAL, Literal: "unterminated raw string"}
+		}
 	case ':':
 		token = newToken(COLON, l.ch)
 	case 0:
@@ -125,26 +229,38 @@ func (l *Lexer) NextToken() Token {
 		case unicode.IsLetter(l.ch):
 			token.Literal = l.readIdentifier()
 			token.Type = LookupIdent(token.Literal)
+			token.Pos = pos
 			// Early return as readIdentifier calls readChar repeatedly
 			// so it does not need to be called again later
 			return token
 
 		case unicode.IsDigit(l.ch):
-			token.Literal = l.readNumber()
-			token.Type = INT
+			token.Literal, token.Type = l.readNumber()
+			token.Pos = pos
 			// Another early return as readNumber will also repeatedly call
 			// readChar
 			return token
 
 		default:
-			token = newToken(ILLEGAL, l.ch)
+			token = Token{Type: ILLEGAL, Literal: fmt.Sprintf("unexpected character %q", l.ch)}
 		}
 	}
 
 	l.readChar()
+
+	if token.Is(COMMENT) && !l.emitComments {
+		return l.NextToken()
+	}
+
+	token.Pos = pos
 	return token
 }
 
+// currentPosition returns the Position of the char under examination
+func (l *Lexer) currentPosition() Position {
+	return Position{Filename: l.filename, Line: l.line, Column: l.column, Offset: l.offset}
+}
+
 // readIdentifier reads l.ch so long as it is a valid utf-8 letter
 // and advances the index until it reaches a non-letter character
 // upon which it will return the string of valid letters i.e. the identifier
@@ -161,24 +277,156 @@ func (l *Lexer) readIdentifier() string {
 // readNumber reads l.ch so long as it a valid digit
 // and advances the index until it reaches a non-digit character
 // upon which it will return the string of valid digits
-func (l *Lexer) readNumber() string {
+// If the digits are followed by a '.' and more digits it reads
+// those too and returns a FLOAT rather than an INT
+func (l *Lexer) readNumber() (string, TokenType) {
 	position := l.position
+	tokenType := TokenType(INT)
 	for unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 
-	return l.input.Slice(position, l.position)
+	if l.ch == '.' && unicode.IsDigit(l.peekChar()) {
+		tokenType = FLOAT
+		l.readChar()
+		for unicode.IsDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	return l.input.Slice(position, l.position), tokenType
+}
+
+// readString reads a double quoted string, leaving the closing quote under examination
+// and returns its value with the escape sequences replaced
+// If the string is malformed it returns a description of the first problem as err
+// but still reads to the closing quote so lexing can carry on after it
+func (l *Lexer) readString() (value, err string) {
+	var b strings.Builder
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return "", "unterminated string"
+		case '"':
+			return b.String(), err
+		case '\\':
+			l.readChar()
+			if l.ch == 0 {
+				return "", "unterminated string"
+			}
+			char, problem := l.readEscape()
+			if problem != "" && err == "" {
+				err = problem
+			}
+			b.WriteRune(char)
+		default:
+			b.WriteRune(l.ch)
+		}
+	}
+}
+
+// readEscape decodes the escape sequence whose first char, after the backslash
+// is under examination, leaving the last char of the sequence under examination
+func (l *Lexer) readEscape() (char rune, err string) {
+	switch l.ch {
+	case 'n':
+		return '\n', ""
+	case 't':
+		return '\t', ""
+	case '"':
+		return '"', ""
+	case '\\':
+		return '\\', ""
+	case 'u':
+		return l.readUnicodeEscape()
+	default:
+		return 0, fmt.Sprintf("unknown escape sequence \\%c", l.ch)
+	}
 }
 
-func (l *Lexer) readString() string {
+// readUnicodeEscape decodes a \u{...} escape of 1 to 6 hex digits with the 'u' under examination
+func (l *Lexer) readUnicodeEscape() (char rune, err string) {
+	if l.peekChar() != '{' {
+		return 0, "unicode escape must be of the form \\u{...}"
+	}
+	l.readChar()
+
+	var digits strings.Builder
+	for l.peekChar() != '}' {
+		if l.peekChar() == '"' || l.peekChar() == 0 {
+			return 0, "unterminated unicode escape"
+		}
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+	l.readChar()
+
+	hex := digits.String()
+	if hex == "" || len(hex) > 6 {
+		return 0, fmt.Sprintf("unicode escape \\u{%s} must have 1 to 6 hex digits", hex)
+	}
+
+	value, parseErr := strconv.ParseUint(hex, 16, 32)
+	if parseErr != nil {
+		return 0, fmt.Sprintf("unicode escape \\u{%s} is not valid hex", hex)
+	}
+
+	char = rune(value)
+	if !utf8.ValidRune(char) {
+		return 0, fmt.Sprintf("unicode escape \\u{%s} is not a valid character", hex)
+	}
+
+	return char, ""
+}
+
+// readRawString reads a backtick quoted string, which has no escape sequences
+// leaving the closing backtick under examination. ok is false if the input ran out first
+func (l *Lexer) readRawString() (value string, ok bool) {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case 0:
+			return "", false
+		case '`':
+			return l.input.Slice(position, l.position), true
 		}
 	}
-	return l.input.Slice(position, l.position)
+}
+
+// readLineComment reads a // comment up to but not including the end of the line
+// leaving the last char of the comment under examination
+func (l *Lexer) readLineComment() string {
+	position := l.position
+	for l.peekChar() != '\n' && l.peekChar() != 0 {
+		l.readChar()
+	}
+
+	return l.input.Slice(position, l.position+1)
+}
+
+// readBlockComment reads a /* ... */ comment, which may nest, leaving the final '/'
+// under examination. ok is false if the input ran out before the comment was closed
+func (l *Lexer) readBlockComment() (comment string, ok bool) {
+	position := l.position
+	depth := 0
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				return l.input.Slice(position, l.position+1), true
+			}
+		}
+		l.readChar()
+	}
+
+	return l.input.Slice(position, l.position), false
 }
 
 // skipWhiteSpace allows us to easily skip all whitespace characters
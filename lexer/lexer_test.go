@@ -11,7 +11,7 @@ func TestNextToken(t *testing.T) {
 	};
 	
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -22,6 +22,8 @@ func TestNextToken(t *testing.T) {
 
 	10 == 10;
 	10 != 9;
+	a && b || c;
+	5 <= 10 >= 5 % 2;
 	"foobar"
 	"foo bar"
 	[1, 2, 3];
@@ -105,6 +107,20 @@ func TestNextToken(t *testing.T) {
 		{NOTEQ, "!="},
 		{INT, "9"},
 		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{AND, "&&"},
+		{IDENT, "b"},
+		{OR, "||"},
+		{IDENT, "c"},
+		{SEMICOLON, ";"},
+		{INT, "5"},
+		{LTEQ, "<="},
+		{INT, "10"},
+		{GTEQ, ">="},
+		{INT, "5"},
+		{PERCENT, "%"},
+		{INT, "2"},
+		{SEMICOLON, ";"},
 		{STRING, "foobar"},
 		{STRING, "foo bar"},
 		{LBRACKET, "["},
@@ -139,3 +155,326 @@ func TestNextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  "héllo" + x;
+ü`
+
+	tests := []struct {
+		expectedType TokenType
+		expectedPos  Position
+	}{
+		{LET, Position{Filename: "test.monkey", Line: 1, Column: 1, Offset: 0}},
+		{IDENT, Position{Filename: "test.monkey", Line: 1, Column: 5, Offset: 4}},
+		{ASSIGN, Position{Filename: "test.monkey", Line: 1, Column: 7, Offset: 6}},
+		{INT, Position{Filename: "test.monkey", Line: 1, Column: 9, Offset: 8}},
+		{SEMICOLON, Position{Filename: "test.monkey", Line: 1, Column: 10, Offset: 9}},
+		{STRING, Position{Filename: "test.monkey", Line: 2, Column: 3, Offset: 13}},
+		// é is 2 bytes but 1 column
+		{PLUS, Position{Filename: "test.monkey", Line: 2, Column: 11, Offset: 22}},
+		{IDENT, Position{Filename: "test.monkey", Line: 2, Column: 13, Offset: 24}},
+		{SEMICOLON, Position{Filename: "test.monkey", Line: 2, Column: 14, Offset: 25}},
+		{IDENT, Position{Filename: "test.monkey", Line: 3, Column: 1, Offset: 27}},
+		{EOF, Position{Filename: "test.monkey", Line: 3, Column: 2, Offset: 29}},
+	}
+
+	l := NewWithFilename("test.monkey", input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type: got %q, wanted %q", i, token.Type, tt.expectedType)
+		}
+
+		if token.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - wrong position for %q: got %+v, wanted %+v", i, token.Literal, token.Pos, tt.expectedPos)
+		}
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{Position{Filename: "main.monkey", Line: 3, Column: 7}, "main.monkey:3:7"},
+		{Position{Line: 1, Column: 2}, "1:2"},
+		{Position{}, "-"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("wrong position string: got %q, wanted %q", got, tt.want)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a line comment
+let x = 5; // trailing
+/* a block
+   comment */ x /* /* nested */ still comment */ / 2
+#`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{COMMENT, "// a line comment", 1},
+		{LET, "let", 2},
+		{IDENT, "x", 2},
+		{ASSIGN, "=", 2},
+		{INT, "5", 2},
+		{SEMICOLON, ";", 2},
+		{COMMENT, "// trailing", 2},
+		{COMMENT, "/* a block\n   comment */", 3},
+		{IDENT, "x", 4},
+		{COMMENT, "/* /* nested */ still comment */", 4},
+		{SLASH, "/", 4},
+		{INT, "2", 4},
+		{ILLEGAL, "unexpected character '#'", 5},
+		{EOF, "", 5},
+	}
+
+	t.Run("emitted", func(t *testing.T) {
+		l := New(input)
+		l.EmitComments(true)
+
+		for i, tt := range tests {
+			token := l.NextToken()
+
+			if token.Type != tt.expectedType {
+				t.Fatalf("tests[%d] - wrong token type: got %q, wanted %q", i, token.Type, tt.expectedType)
+			}
+
+			if token.Literal != tt.expectedLiteral {
+				t.Fatalf("tests[%d] - wrong token literal: got %q, wanted %q", i, token.Literal, tt.expectedLiteral)
+			}
+
+			if token.Pos.Line != tt.expectedLine {
+				t.Errorf("tests[%d] - wrong line for %q: got %d, wanted %d", i, token.Literal, token.Pos.Line, tt.expectedLine)
+			}
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		l := New(input)
+
+		for i, tt := range tests {
+			if tt.expectedType == COMMENT {
+				continue
+			}
+
+			token := l.NextToken()
+
+			if token.Type != tt.expectedType {
+				t.Fatalf("tests[%d] - wrong token type: got %q, wanted %q", i, token.Type, tt.expectedType)
+			}
+
+			if token.Literal != tt.expectedLiteral {
+				t.Fatalf("tests[%d] - wrong token literal: got %q, wanted %q", i, token.Literal, tt.expectedLiteral)
+			}
+		}
+	})
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("let x = 1; /* never /* closed */")
+
+	// Skip let x = 1;
+	for i := 0; i < 5; i++ {
+		l.NextToken()
+	}
+
+	token := l.NextToken()
+	if token.Type != ILLEGAL {
+		t.Fatalf("wrong token type: got %q, wanted %q", token.Type, ILLEGAL)
+	}
+
+	if token.Literal != "unterminated block comment" {
+		t.Errorf("wrong token literal: got %q", token.Literal)
+	}
+
+	if token.Pos.Column != 12 {
+		t.Errorf("wrong column: got %d, wanted 12", token.Pos.Column)
+	}
+
+	if eof := l.NextToken(); eof.Type != EOF {
+		t.Errorf("expected EOF after unterminated comment, got %q", eof.Type)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{`"plain"`, STRING, "plain"},
+		{`"line\nbreak"`, STRING, "line\nbreak"},
+		{`"tab\tbed"`, STRING, "tab\tbed"},
+		{`"say \"hi\""`, STRING, `say "hi"`},
+		{`"back\\slash"`, STRING, `back\slash`},
+		{`"\u{41}\u{e9}\u{1F600}"`, STRING, "Aé😀"},
+		{"`raw \\n \"string\"`", STRING, `raw \n "string"`},
+		{"`multi\nline`", STRING, "multi\nline"},
+		{`""`, STRING, ""},
+		{"``", STRING, ""},
+		{`"never closed`, ILLEGAL, "unterminated string"},
+		{`"ends in \`, ILLEGAL, "unterminated string"},
+		{"`never closed", ILLEGAL, "unterminated raw string"},
+		{`"bad \q escape"`, ILLEGAL, `unknown escape sequence \q`},
+		{`"\u41"`, ILLEGAL, `unicode escape must be of the form \u{...}`},
+		{`"\u{}"`, ILLEGAL, `unicode escape \u{} must have 1 to 6 hex digits`},
+		{`"\u{1234567}"`, ILLEGAL, `unicode escape \u{1234567} must have 1 to 6 hex digits`},
+		{`"\u{zz}"`, ILLEGAL, `unicode escape \u{zz} is not valid hex`},
+		{`"\u{D800}"`, ILLEGAL, `unicode escape \u{D800} is not a valid character`},
+		{`"\u{41"`, ILLEGAL, "unterminated unicode escape"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		token := l.NextToken()
+
+		if token.Type != tt.expectedType {
+			t.Errorf("%s: wrong token type: got %q, wanted %q", tt.input, token.Type, tt.expectedType)
+		}
+
+		if token.Literal != tt.expectedLiteral {
+			t.Errorf("%s: wrong token literal: got %q, wanted %q", tt.input, token.Literal, tt.expectedLiteral)
+		}
+	}
+}
+
+func TestLexingContinuesAfterBadString(t *testing.T) {
+	l := New(`"bad \q" + "fine"`)
+
+	want := []TokenType{ILLEGAL, PLUS, STRING, EOF}
+	for i, tokenType := range want {
+		token := l.NextToken()
+		if token.Type != tokenType {
+			t.Fatalf("tokens[%d] - wrong token type: got %q, wanted %q", i, token.Type, tokenType)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{"5", INT, "5"},
+		{"3.14", FLOAT, "3.14"},
+		{"0.5", FLOAT, "0.5"},
+		{"10.0", FLOAT, "10.0"},
+	}
+
+	for _, tt := range tests {
+		token := New(tt.input).NextToken()
+
+		if token.Type != tt.expectedType {
+			t.Errorf("%s: wrong token type: got %q, wanted %q", tt.input, token.Type, tt.expectedType)
+		}
+
+		if token.Literal != tt.expectedLiteral {
+			t.Errorf("%s: wrong token literal: got %q, wanted %q", tt.input, token.Literal, tt.expectedLiteral)
+		}
+	}
+
+	// A '.' not followed by a digit is not part of the number
+	l := New("1.")
+	if token := l.NextToken(); token.Type != INT || token.Literal != "1" {
+		t.Errorf("1.: wrong first token: got %q %q, wanted INT \"1\"", token.Type, token.Literal)
+	}
+	if token := l.NextToken(); token.Type != ILLEGAL {
+		t.Errorf("1.: wrong second token: got %q, wanted ILLEGAL", token.Type)
+	}
+}
+
+func TestSingleAmpersandAndPipe(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"&", "unexpected character '&', did you mean '&&'?"},
+		{"|", "unexpected character '|', did you mean '||'?"},
+	}
+
+	for _, tt := range tests {
+		token := New(tt.input).NextToken()
+		if token.Type != ILLEGAL {
+			t.Errorf("%s: wrong token type: got %q, wanted %q", tt.input, token.Type, ILLEGAL)
+		}
+
+		if token.Literal != tt.want {
+			t.Errorf("%s: wrong token literal: got %q, wanted %q", tt.input, token.Literal, tt.want)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T) {
+	input := `while for in inside break continue`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{WHILE, "while"},
+		{FOR, "for"},
+		{IN, "in"},
+		{IDENT, "inside"},
+		{BREAK, "break"},
+		{CONTINUE, "continue"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type: got %q, wanted %q", i, token.Type, tt.expectedType)
+		}
+
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal: got %q, wanted %q", i, token.Literal, tt.expectedLiteral)
+		}
+	}
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; x == 6; x -1`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{IDENT, "x"}, {ASSIGN, "="}, {INT, "1"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {PLUSASSIGN, "+="}, {INT, "2"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {MINUSASSIGN, "-="}, {INT, "3"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {ASTERISKASSIGN, "*="}, {INT, "4"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {SLASHASSIGN, "/="}, {INT, "5"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {EQ, "=="}, {INT, "6"}, {SEMICOLON, ";"},
+		{IDENT, "x"}, {MINUS, "-"}, {INT, "1"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type: got %q, wanted %q", i, token.Type, tt.expectedType)
+		}
+
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal: got %q, wanted %q", i, token.Literal, tt.expectedLiteral)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package lexer
 
+import "fmt"
+
 const (
-	ILLEGAL = "ILLEGAL"
+	ILLEGAL = "ILLEGAL" // The literal describes what was wrong
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // Only emitted if asked for, see Lexer.EmitComments
 
 	// Identifiers and literals
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
 	// Operators
@@ -18,8 +22,19 @@ const (
 	SLASH    = "/"
 	LT       = "<"
 	GT       = ">"
+	LTEQ     = "<="
+	GTEQ     = ">="
+	PERCENT  = "%"
 	EQ       = "=="
 	NOTEQ    = "!="
+	AND      = "&&"
+	OR       = "||"
+
+	// Compound assignment
+	PLUSASSIGN     = "+="
+	MINUSASSIGN    = "-="
+	ASTERISKASSIGN = "*="
+	SLASHASSIGN    = "/="
 
 	// Delimiters
 	COMMA     = ","
@@ -40,14 +55,47 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 )
 
 type TokenType string
 
+// Position is a location in Monkey source code
+type Position struct {
+	Filename string // The name of the source file, may be empty
+	Line     int    // 1 based line number
+	Column   int    // 1 based column number, counted in characters not bytes
+	Offset   int    // 0 based byte offset into the source
+}
+
+// IsValid reports whether the Position refers to a real location
+// the zero Position does not
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String renders the Position as file:line:col, or line:col if there is no filename
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 // Token represents a lexical token for monkey
 type Token struct {
 	Type    TokenType // The type of token
 	Literal string    // The token's literal string value
+	Pos     Position  // Where the token starts in the source
 }
 
 // Is returns whether or not the calling Token is of type 'tokenType'
@@ -57,13 +105,18 @@ func (t Token) Is(tokenType TokenType) bool {
 
 // keywords are the monkey keywords mapped to their Tokens
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
 // LookupIdent checks to see if 'ident' is an accepted keyword
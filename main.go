@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
@@ -14,6 +15,16 @@ var (
 )
 
 func main() {
+	engineFlag := flag.String("engine", string(repl.EngineVM), "backend to run code with: eval or vm")
+	checkedFlag := flag.Bool("checked", false, "report integer overflow as an error rather than promoting to a big integer")
+	flag.Parse()
+
+	engine, err := repl.ParseEngine(*engineFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -22,7 +33,8 @@ func main() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Version: %s\n", version)
 	fmt.Printf("Commit: %s\n", commit)
+	fmt.Printf("Engine: %s\n", engine)
 	fmt.Println("Type some commands...")
 
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, repl.Options{Engine: engine, Checked: *checkedFlag})
 }
@@ -0,0 +1,128 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Integer arithmetic shared by the evaluator and the VM, results that don't
+// fit in an int are promoted to big integers rather than wrapping around
+
+// CheckedAdd returns a + b and whether the result overflowed
+func CheckedAdd(a, b int) (int, bool) {
+	sum := a + b
+	// Overflow if both operands have the same sign and the result's sign differs
+	return sum, (a >= 0) == (b >= 0) && (sum >= 0) != (a >= 0)
+}
+
+// CheckedSub returns a - b and whether the result overflowed
+func CheckedSub(a, b int) (int, bool) {
+	diff := a - b
+	// Overflow if the operands have different signs and the result's sign differs from a's
+	return diff, (a >= 0) != (b >= 0) && (diff >= 0) != (a >= 0)
+}
+
+// CheckedMul returns a * b and whether the result overflowed
+func CheckedMul(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+
+	product := a * b
+	// -1 * MinInt is the one case that divides back cleanly despite overflowing
+	overflow := product/b != a || (a == -1 && b == product) || (b == -1 && a == product)
+	return product, overflow
+}
+
+// IntegerArithmetic applies operator, one of +, -, *, / or %, to a and b
+// if the result doesn't fit in an int it is promoted to a big integer and overflow is true
+// Division and modulo truncate towards zero like Go, the caller must check b is not zero
+func IntegerArithmetic(operator string, a, b *Integer) (result *Integer, overflow bool) {
+	if !a.IsBig() && !b.IsBig() {
+		var value int
+		switch operator {
+		case "+":
+			value, overflow = CheckedAdd(a.Value, b.Value)
+		case "-":
+			value, overflow = CheckedSub(a.Value, b.Value)
+		case "*":
+			value, overflow = CheckedMul(a.Value, b.Value)
+		case "/":
+			// The only int division that overflows
+			overflow = a.Value == math.MinInt && b.Value == -1
+			value = a.Value / b.Value
+		case "%":
+			value = a.Value % b.Value
+		}
+
+		if !overflow {
+			return &Integer{Value: value}, false
+		}
+	}
+
+	x, y := a.BigInt(), b.BigInt()
+	switch operator {
+	case "+":
+		x.Add(x, y)
+	case "-":
+		x.Sub(x, y)
+	case "*":
+		x.Mul(x, y)
+	case "/":
+		x.Quo(x, y)
+	case "%":
+		x.Rem(x, y)
+	}
+
+	result = NewBigInteger(x)
+	return result, result.IsBig()
+}
+
+// CompareIntegers returns -1 if a < b, 0 if a == b and +1 if a > b
+func CompareIntegers(a, b *Integer) int {
+	if !a.IsBig() && !b.IsBig() {
+		switch {
+		case a.Value < b.Value:
+			return -1
+		case a.Value > b.Value:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return a.BigInt().Cmp(b.BigInt())
+}
+
+// NegateInteger returns -i, promoting to a big integer if needed
+func NegateInteger(i *Integer) *Integer {
+	if !i.IsBig() && i.Value != math.MinInt {
+		return &Integer{Value: -i.Value}
+	}
+
+	return NewBigInteger(new(big.Int).Neg(i.BigInt()))
+}
+
+// IntegerToFloat converts i to the nearest float64
+func IntegerToFloat(i *Integer) float64 {
+	if !i.IsBig() {
+		return float64(i.Value)
+	}
+
+	f, _ := new(big.Float).SetInt(i.Big).Float64()
+	return f
+}
+
+// IsNumber reports whether obj is an Integer or a Float
+func IsNumber(obj Object) bool {
+	return obj.Type() == INTEGER || obj.Type() == FLOAT
+}
+
+// ToFloat converts an Integer or Float to a float64, obj must satisfy IsNumber
+func ToFloat(obj Object) float64 {
+	if i, ok := obj.(*Integer); ok {
+		return IntegerToFloat(i)
+	}
+
+	return obj.(*Float).Value
+}
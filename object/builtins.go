@@ -0,0 +1,156 @@
+package object
+
+import (
+	"fmt"
+)
+
+// Builtins is the ordered registry of builtin functions shared by the evaluator
+// and the VM, the compiler refers to them by their index so the order
+// here must never change, only append to it
+//
+// A builtin returns nil if it has no meaningful result, it is up to
+// the caller to turn that into the appropriate NULL
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		Name: "len",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments: got %d, wanted %d", len(args), 1)
+				}
+
+				switch arg := args[0].(type) {
+				case *String:
+					return &Integer{Value: len(arg.Value)}
+
+				case *Array:
+					return &Integer{Value: len(arg.Elements)}
+
+				default:
+					return newError("argument to `len` not supported: %s", args[0].Type())
+				}
+			},
+		},
+	},
+	{
+		Name: "print",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				for _, arg := range args {
+					fmt.Println(arg.Inspect())
+				}
+
+				return nil
+			},
+		},
+	},
+	{
+		Name: "first",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments: got %d, wanted %d", len(args), 1)
+				}
+
+				if args[0].Type() != ARRAY {
+					return newError("argument to `first` must be an ARRAY, got %s", args[0].Type())
+				}
+
+				arr := args[0].(*Array)
+				if len(arr.Elements) > 0 {
+					return arr.Elements[0]
+				}
+
+				return nil
+			},
+		},
+	},
+	{
+		Name: "last",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments: got %d, wanted %d", len(args), 1)
+				}
+
+				if args[0].Type() != ARRAY {
+					return newError("argument to `last` must be an ARRAY, got %s", args[0].Type())
+				}
+
+				arr := args[0].(*Array)
+				length := len(arr.Elements)
+				if length > 0 {
+					return arr.Elements[length-1]
+				}
+
+				return nil
+			},
+		},
+	},
+	{
+		Name: "rest",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments: got %d, wanted %d", len(args), 1)
+				}
+
+				if args[0].Type() != ARRAY {
+					return newError("argument to `rest` must be an ARRAY, got %s", args[0].Type())
+				}
+
+				arr := args[0].(*Array)
+				length := len(arr.Elements)
+				if length > 0 {
+					newElements := make([]Object, length-1)
+					copy(newElements, arr.Elements[1:length])
+					return &Array{Elements: newElements}
+				}
+
+				return nil
+			},
+		},
+	},
+	{
+		Name: "push",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 2 {
+					return newError("wrong number of arguments: got %d, wanted %d", len(args), 2)
+				}
+
+				if args[0].Type() != ARRAY {
+					return newError("argument to `push` must be an ARRAY, got %s", args[0].Type())
+				}
+
+				arr := args[0].(*Array)
+				length := len(arr.Elements)
+
+				newElements := make([]Object, length+1)
+				copy(newElements, arr.Elements)
+				newElements[length] = args[1]
+
+				return &Array{Elements: newElements}
+			},
+		},
+	},
+}
+
+// GetBuiltinByName returns the builtin registered under name, or nil
+// if there is no such builtin
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -3,6 +3,10 @@ package object
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+
+	// checked is whether integer arithmetic reports overflow rather than promoting
+	// only the outermost environment's setting is used
+	checked bool
 }
 
 func NewEnvironment() *Environment {
@@ -16,6 +20,22 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// SetCheckedArithmetic turns on (or off) reporting integer arithmetic whose result
+// doesn't fit in an int as an error, by default it is promoted to a big integer
+func (e *Environment) SetCheckedArithmetic(checked bool) {
+	e.checked = checked
+}
+
+// CheckedArithmetic reports whether integer overflow is an error
+// in this environment, see SetCheckedArithmetic
+func (e *Environment) CheckedArithmetic() bool {
+	if e.outer != nil {
+		return e.outer.CheckedArithmetic()
+	}
+
+	return e.checked
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -28,3 +48,19 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Assign updates an existing binding for name in the environment it was
+// defined in, rather than shadowing it like Set. It reports false if
+// name isn't bound anywhere
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package object
+
+import "sort"
+
+// Iterate returns the elements a for loop over obj visits, in order
+// the elements of an array, each character of a string as a string
+// or the keys of a hash. ok is false if obj can't be iterated over
+func Iterate(obj Object) (elements []Object, ok bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+
+	case *String:
+		elements := []Object{}
+		for _, char := range obj.Value {
+			elements = append(elements, &String{Value: string(char)})
+		}
+		return elements, true
+
+	case *Hash:
+		return obj.Keys(), true
+
+	default:
+		return nil, false
+	}
+}
+
+// Keys returns the keys of the hash in a stable order: grouped by type and
+// then ascending, so that iterating over a hash gives the same result every time
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keyLess(keys[i], keys[j])
+	})
+
+	return keys
+}
+
+// keyLess reports whether hash key a sorts before hash key b
+func keyLess(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return CompareIntegers(a, b.(*Integer)) < 0
+	case *Float:
+		return a.Value < b.(*Float).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	default:
+		return false
+	}
+}
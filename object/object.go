@@ -4,22 +4,37 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/FollowTheProcess/monkey/ast"
+	"github.com/FollowTheProcess/monkey/code"
+	"github.com/FollowTheProcess/monkey/lexer"
 )
 
 const (
 	INTEGER  = "INTEGER"
+	FLOAT    = "FLOAT"
 	BOOLEAN  = "BOOLEAN"
 	NULL     = "NULL"
 	RETURN   = "RETURN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 	ERROR    = "ERROR"
 	FUNCTION = "FUNCTION"
 	STRING   = "STRING"
 	BUILTIN  = "BUILTIN"
 	ARRAY    = "ARRAY"
 	HASH     = "HASH"
+
+	COMPILED_FUNCTION = "COMPILED_FUNCTION"
+	CLOSURE           = "CLOSURE"
+
+	// BIG_INTEGER is only used as a HashKey type so hashed big integers can't
+	// collide with small ones, big integers are still of type INTEGER
+	BIG_INTEGER = "BIG_INTEGER"
 )
 
 type ObjectType string
@@ -43,6 +58,8 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash is mutable, 'h[k] = v' changes it in place so the
+// change is seen through every binding that refers to it
 type Hash struct {
 	Pairs map[HashKey]HashPair
 }
@@ -64,11 +81,48 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Integer is an arbitrary precision integer, small values are held in Value
+// and only those that don't fit in an int are held in Big. Use NewBigInteger
+// to make an Integer from a big.Int so that holds and equal Integers always
+// look the same
 type Integer struct {
 	Value int
+	Big   *big.Int // Nil unless the value doesn't fit in an int
+}
+
+// NewBigInteger returns an Integer with the value of b, which it takes ownership of
+func NewBigInteger(b *big.Int) *Integer {
+	if b.IsInt64() && b.Int64() >= math.MinInt && b.Int64() <= math.MaxInt {
+		return &Integer{Value: int(b.Int64())}
+	}
+
+	return &Integer{Big: b}
+}
+
+// IsBig reports whether the value is too big to fit in an int
+func (i *Integer) IsBig() bool {
+	return i.Big != nil
+}
+
+// IsZero reports whether the value is 0
+func (i *Integer) IsZero() bool {
+	return !i.IsBig() && i.Value == 0
+}
+
+// BigInt returns the value as a new big.Int the caller may modify
+func (i *Integer) BigInt() *big.Int {
+	if i.IsBig() {
+		return new(big.Int).Set(i.Big)
+	}
+
+	return big.NewInt(int64(i.Value))
 }
 
 func (i *Integer) Inspect() string {
+	if i.IsBig() {
+		return i.Big.String()
+	}
+
 	return fmt.Sprintf("%d", i.Value)
 }
 
@@ -77,9 +131,43 @@ func (i *Integer) Type() ObjectType {
 }
 
 func (i *Integer) HashKey() HashKey {
+	if i.IsBig() {
+		h := fnv.New64a()
+		h.Write([]byte(i.Big.String()))
+
+		return HashKey{Type: BIG_INTEGER, Value: h.Sum64()}
+	}
+
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+// Inspect always includes a decimal point so floats can't be mistaken for integers
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+
+	return s + ".0"
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT
+}
+
+func (f *Float) HashKey() HashKey {
+	// 0.0 and -0.0 are equal so must hash the same
+	if f.Value == 0 {
+		return HashKey{Type: f.Type(), Value: 0}
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -121,12 +209,31 @@ type Return struct {
 func (r *Return) Type() ObjectType { return RETURN }
 func (r *Return) Inspect() string  { return r.Value.Inspect() }
 
+// Break is passed up from a break statement to the loop it leaves
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK }
+func (b *Break) Inspect() string  { return "break" }
+
+// Continue is passed up from a continue statement to the loop it skips ahead
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Error struct {
 	Message string
+	Pos     lexer.Position // Where the error happened, if known
 }
 
 func (e *Error) Type() ObjectType { return ERROR }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("ERROR: %s: %s", e.Pos, e.Message)
+	}
+	return "ERROR: " + e.Message
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
@@ -177,6 +284,8 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectType { return BUILTIN }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Array is mutable, 'arr[i] = v' changes it in place so the
+// change is seen through every binding that refers to it
 type Array struct {
 	Elements []Object
 }
@@ -197,3 +306,31 @@ func (a *Array) Inspect() string {
 
 	return out.String()
 }
+
+// CompiledFunction is a function compiled to bytecode, run by the VM
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	SourceMap     code.SourceMap // Source positions of Instructions, for errors
+	NumLocals     int            // How many local bindings the function creates
+	LocalNames    []string       // Names of the locals by index, for errors
+	NumParameters int
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION }
+
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure is a CompiledFunction along with the free variables
+// it captured when it was created
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE }
+
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +25,219 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings %q and %q are different but have identical hash keys", hello1.Value, diff1.Value)
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	if (&Float{Value: 1.5}).HashKey() != (&Float{Value: 1.5}).HashKey() {
+		t.Errorf("identical floats have different hash keys")
+	}
+
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 2.5}).HashKey() {
+		t.Errorf("different floats have identical hash keys")
+	}
+
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0.0 and -0.0 are equal but have different hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.want {
+			t.Errorf("wrong Inspect for %g: got %q, wanted %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(a, b int) (int, bool)
+		a, b     int
+		want     int
+		overflow bool
+	}{
+		{"add", CheckedAdd, 1, 2, 3, false},
+		{"add negative", CheckedAdd, -5, 3, -2, false},
+		{"add max", CheckedAdd, math.MaxInt, 1, math.MinInt, true},
+		{"add min", CheckedAdd, math.MinInt, -1, math.MaxInt, true},
+		{"add max and min", CheckedAdd, math.MaxInt, math.MinInt, -1, false},
+		{"sub", CheckedSub, 1, 2, -1, false},
+		{"sub min", CheckedSub, math.MinInt, 1, math.MaxInt, true},
+		{"sub max", CheckedSub, math.MaxInt, -1, math.MinInt, true},
+		{"sub zero min", CheckedSub, 0, math.MinInt, math.MinInt, true},
+		{"sub min min", CheckedSub, math.MinInt, math.MinInt, 0, false},
+		{"mul", CheckedMul, 6, 7, 42, false},
+		{"mul zero", CheckedMul, 0, math.MaxInt, 0, false},
+		{"mul max", CheckedMul, math.MaxInt, 2, -2, true},
+		{"mul min by -1", CheckedMul, math.MinInt, -1, math.MinInt, true},
+		{"mul -1 by min", CheckedMul, -1, math.MinInt, math.MinInt, true},
+		{"mul -1", CheckedMul, -1, math.MaxInt, -math.MaxInt, false},
+		{"mul large", CheckedMul, math.MaxInt/2 + 1, 2, math.MinInt, true},
+	}
+
+	for _, tt := range tests {
+		got, overflow := tt.fn(tt.a, tt.b)
+		if overflow != tt.overflow {
+			t.Errorf("%s: wrong overflow for %d and %d: got %t, wanted %t", tt.name, tt.a, tt.b, overflow, tt.overflow)
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: wrong result for %d and %d: got %d, wanted %d", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func bigFromString(t *testing.T, s string) *big.Int {
+	t.Helper()
+
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad big integer %q", s)
+	}
+
+	return b
+}
+
+func TestNewBigIntegerNormalises(t *testing.T) {
+	small := NewBigInteger(big.NewInt(42))
+	if small.IsBig() || small.Value != 42 {
+		t.Errorf("42 should be held as an int, got %+v", small)
+	}
+
+	large := NewBigInteger(bigFromString(t, "1180591620717411303424"))
+	if !large.IsBig() || large.Inspect() != "1180591620717411303424" {
+		t.Errorf("2^70 should be held as a big.Int, got %+v", large)
+	}
+}
+
+func TestBigIntegerHashKey(t *testing.T) {
+	// 2^70 from a literal and from arithmetic must hash the same
+	literal := NewBigInteger(bigFromString(t, "1180591620717411303424"))
+	computed, _ := IntegerArithmetic("*", &Integer{Value: 1 << 40}, &Integer{Value: 1 << 30})
+
+	if literal.HashKey() != computed.HashKey() {
+		t.Errorf("equal big integers have different hash keys")
+	}
+
+	// And a big result that comes back into range must hash like a small one
+	back, _ := IntegerArithmetic("-", literal, computed)
+	if back.HashKey() != (&Integer{Value: 0}).HashKey() {
+		t.Errorf("0 computed from big integers has a different hash key to 0")
+	}
+
+	other := NewBigInteger(bigFromString(t, "1180591620717411303425"))
+	if literal.HashKey() == other.HashKey() {
+		t.Errorf("different big integers have identical hash keys")
+	}
+
+	// 2^63 hashes to the same value as this small integer, the types must keep them apart
+	collides := NewBigInteger(bigFromString(t, "9223372036854775808"))
+	if collides.HashKey() == (&Integer{Value: -590260884831411150}).HashKey() {
+		t.Errorf("big integer has the same hash key as a small integer")
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		operator string
+		a, b     string
+		want     string
+		overflow bool
+	}{
+		{"+", "1", "2", "3", false},
+		{"+", "9223372036854775807", "1", "9223372036854775808", true},
+		{"-", "-9223372036854775808", "1", "-9223372036854775809", true},
+		{"*", "4294967296", "4294967296", "18446744073709551616", true},
+		{"/", "-9223372036854775808", "-1", "9223372036854775808", true},
+		{"/", "-7", "2", "-3", false},
+		{"%", "-7", "2", "-1", false},
+		{"/", "18446744073709551616", "4294967296", "4294967296", false},
+		{"%", "18446744073709551617", "4294967296", "1", false},
+		{"/", "-18446744073709551617", "4294967296", "-4294967296", false},
+		{"%", "-18446744073709551617", "4294967296", "-1", false},
+		{"-", "18446744073709551616", "18446744073709551615", "1", false},
+	}
+
+	for _, tt := range tests {
+		a := NewBigInteger(bigFromString(t, tt.a))
+		b := NewBigInteger(bigFromString(t, tt.b))
+
+		got, overflow := IntegerArithmetic(tt.operator, a, b)
+		if got.Inspect() != tt.want {
+			t.Errorf("%s %s %s: got %s, wanted %s", tt.a, tt.operator, tt.b, got.Inspect(), tt.want)
+		}
+
+		if overflow != tt.overflow {
+			t.Errorf("%s %s %s: wrong overflow: got %t, wanted %t", tt.a, tt.operator, tt.b, overflow, tt.overflow)
+		}
+	}
+}
+
+func TestCompareAndNegateIntegers(t *testing.T) {
+	huge := NewBigInteger(bigFromString(t, "1180591620717411303424"))
+	small := &Integer{Value: 5}
+
+	if CompareIntegers(huge, small) != 1 || CompareIntegers(small, huge) != -1 || CompareIntegers(huge, huge) != 0 {
+		t.Errorf("wrong comparison of big and small integers")
+	}
+
+	if got := NegateInteger(&Integer{Value: math.MinInt}).Inspect(); got != "9223372036854775808" {
+		t.Errorf("wrong negation of the smallest int: got %s", got)
+	}
+
+	if got := NegateInteger(NegateInteger(huge)); CompareIntegers(got, huge) != 0 {
+		t.Errorf("double negation changed the value: got %s", got.Inspect())
+	}
+}
+
+func TestIterate(t *testing.T) {
+	tests := []struct {
+		obj  Object
+		want []string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "two"}}}, []string{"1", "two"}},
+		{&String{Value: "héy"}, []string{"h", "é", "y"}},
+		{
+			&Hash{Pairs: map[HashKey]HashPair{
+				(&String{Value: "b"}).HashKey():   {Key: &String{Value: "b"}},
+				(&Integer{Value: 10}).HashKey():   {Key: &Integer{Value: 10}},
+				(&String{Value: "a"}).HashKey():   {Key: &String{Value: "a"}},
+				(&Integer{Value: -1}).HashKey():   {Key: &Integer{Value: -1}},
+				(&Boolean{Value: true}).HashKey(): {Key: &Boolean{Value: true}},
+			}},
+			[]string{"true", "-1", "10", "a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		elements, ok := Iterate(tt.obj)
+		if !ok {
+			t.Errorf("%s: could not iterate", tt.obj.Inspect())
+			continue
+		}
+
+		got := []string{}
+		for _, e := range elements {
+			got = append(got, e.Inspect())
+		}
+
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: wrong elements: got %v, wanted %v", tt.obj.Inspect(), got, tt.want)
+		}
+	}
+
+	if _, ok := Iterate(&Integer{Value: 1}); ok {
+		t.Errorf("integers should not be iterable")
+	}
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FollowTheProcess/monkey/lexer"
+)
+
+// Error is a single syntax error found while parsing
+type Error struct {
+	Pos      lexer.Position  // Where the error was found
+	Expected lexer.TokenType // The token the parser wanted, empty if it wasn't waiting on a specific one
+	Actual   lexer.Token     // The token the parser actually got
+	Message  string          // Human readable description of the error
+}
+
+// Error satisfies the error interface, formatted as file:line:col: message
+func (e *Error) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// ErrorList is every syntax error found in a program, in source order
+type ErrorList []*Error
+
+// Error satisfies the error interface, putting each error on its own line
+func (l ErrorList) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the ErrorList as an error, or nil if it is empty
+// so callers can write if err := p.Errors().Err(); err != nil
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
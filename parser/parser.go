@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/FollowTheProcess/monkey/ast"
@@ -11,26 +12,39 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // =, +=, -=, *= or /=
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
-	LESSGREATER // > or <
+	LESSGREATER // >, <, >= or <=
 	SUM         // +
-	PRODUCT     // *
+	PRODUCT     // *, / or %
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX
 )
 
 var precedences = map[lexer.TokenType]int{
-	lexer.EQ:       EQUALS,
-	lexer.NOTEQ:    EQUALS,
-	lexer.LT:       LESSGREATER,
-	lexer.GT:       LESSGREATER,
-	lexer.PLUS:     SUM,
-	lexer.MINUS:    SUM,
-	lexer.SLASH:    PRODUCT,
-	lexer.ASTERISK: PRODUCT,
-	lexer.LPAREN:   CALL,
-	lexer.LBRACKET: INDEX,
+	lexer.ASSIGN:         ASSIGN,
+	lexer.PLUSASSIGN:     ASSIGN,
+	lexer.MINUSASSIGN:    ASSIGN,
+	lexer.ASTERISKASSIGN: ASSIGN,
+	lexer.SLASHASSIGN:    ASSIGN,
+	lexer.OR:             OR,
+	lexer.AND:            AND,
+	lexer.EQ:             EQUALS,
+	lexer.NOTEQ:          EQUALS,
+	lexer.LT:             LESSGREATER,
+	lexer.GT:             LESSGREATER,
+	lexer.LTEQ:           LESSGREATER,
+	lexer.GTEQ:           LESSGREATER,
+	lexer.PLUS:           SUM,
+	lexer.MINUS:          SUM,
+	lexer.SLASH:          PRODUCT,
+	lexer.ASTERISK:       PRODUCT,
+	lexer.PERCENT:        PRODUCT,
+	lexer.LPAREN:         CALL,
+	lexer.LBRACKET:       INDEX,
 }
 
 type (
@@ -40,7 +54,19 @@ type (
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors ErrorList
+
+	// panicking is set on the first error in a statement and cleared once the parser
+	// has synchronised, errors reported in between are cascades of the first so are dropped
+	panicking bool
+
+	// depth is how many braces are open before currentToken, a closing brace
+	// sits at the same depth as the brace it closes
+	depth int
+
+	// loops is how many loops enclose currentToken within the current function,
+	// break and continue are only allowed when it is non zero
+	loops int
 
 	currentToken lexer.Token
 	peekToken    lexer.Token
@@ -52,12 +78,13 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: ErrorList{},
 	}
 
 	p.prefixParseFns = make(map[lexer.TokenType]prefixParseFn)
 	p.registerPrefix(lexer.IDENT, p.parseIdentifier)
 	p.registerPrefix(lexer.INT, p.parseIntegerLiteral)
+	p.registerPrefix(lexer.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(lexer.BANG, p.parsePrefixExpression)
 	p.registerPrefix(lexer.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(lexer.TRUE, p.parseBoolean)
@@ -68,6 +95,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(lexer.STRING, p.parseStringLiteral)
 	p.registerPrefix(lexer.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(lexer.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(lexer.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = make(map[lexer.TokenType]infixParseFn)
 	p.registerInfix(lexer.PLUS, p.parseInfixExpression)
@@ -78,6 +106,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(lexer.NOTEQ, p.parseInfixExpression)
 	p.registerInfix(lexer.LT, p.parseInfixExpression)
 	p.registerInfix(lexer.GT, p.parseInfixExpression)
+	p.registerInfix(lexer.LTEQ, p.parseInfixExpression)
+	p.registerInfix(lexer.GTEQ, p.parseInfixExpression)
+	p.registerInfix(lexer.PERCENT, p.parseInfixExpression)
+	p.registerInfix(lexer.AND, p.parseInfixExpression)
+	p.registerInfix(lexer.OR, p.parseInfixExpression)
+	p.registerInfix(lexer.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(lexer.PLUSASSIGN, p.parseAssignExpression)
+	p.registerInfix(lexer.MINUSASSIGN, p.parseAssignExpression)
+	p.registerInfix(lexer.ASTERISKASSIGN, p.parseAssignExpression)
+	p.registerInfix(lexer.SLASHASSIGN, p.parseAssignExpression)
 	p.registerInfix(lexer.LPAREN, p.parseCallExpression)
 	p.registerInfix(lexer.LBRACKET, p.parseIndexExpression)
 
@@ -89,8 +127,22 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	if p.currentToken.Is(lexer.LBRACE) {
+		p.depth++
+	}
+
 	p.currentToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	// The lexer may have been asked to emit comments for some other tool
+	// they mean nothing to the parser
+	for p.peekToken.Is(lexer.COMMENT) {
+		p.peekToken = p.l.NextToken()
+	}
+
+	if p.currentToken.Is(lexer.RBRACE) {
+		p.depth--
+	}
 }
 
 func (p *Parser) expectPeek(t lexer.TokenType) bool {
@@ -103,19 +155,33 @@ func (p *Parser) expectPeek(t lexer.TokenType) bool {
 	}
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns the syntax errors found while parsing, in source order
+func (p *Parser) Errors() ErrorList {
 	return p.errors
 }
 
+// addError records a parser error about the token got, which is where the error is reported
+// expected may be empty if the parser wasn't waiting on a particular token
+func (p *Parser) addError(expected lexer.TokenType, got lexer.Token, format string, a ...interface{}) {
+	if p.panicking {
+		return
+	}
+
+	p.panicking = true
+	p.errors = append(p.errors, &Error{
+		Pos:      got.Pos,
+		Expected: expected,
+		Actual:   got,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
 func (p *Parser) peekError(t lexer.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(t, p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError("", p.currentToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -124,21 +190,74 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	for !p.currentToken.Is(lexer.EOF) {
 		stmt := p.parseStatement()
-		program.Statements = append(program.Statements, stmt)
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
 		p.nextToken()
 	}
 
 	return program
 }
 
+// parseStatement parses the statement starting at currentToken, if it contains
+// an error the parser synchronises to the end of it and it returns nil
 func (p *Parser) parseStatement() ast.Statement {
+	depth := p.depth
+
+	var stmt ast.Statement
 	switch p.currentToken.Type {
 	case lexer.LET:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case lexer.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case lexer.WHILE:
+		stmt = p.parseWhileStatement()
+	case lexer.FOR:
+		stmt = p.parseForStatement()
+	case lexer.BREAK:
+		stmt = p.parseBreakStatement()
+	case lexer.CONTINUE:
+		stmt = p.parseContinueStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if p.panicking {
+		p.synchronise(depth)
+		return nil
+	}
+
+	return stmt
+}
+
+// synchronise skips the rest of a broken statement that started at the given brace depth,
+// leaving currentToken on its last token so the caller moves on to the next statement
+// as normal. A statement ends at a ';' or '}' at the depth it started at, or just
+// before the '}' that closes the block it is in. A '}' followed by 'else' doesn't end
+// it, so a broken if expression is skipped along with all its branches
+func (p *Parser) synchronise(depth int) {
+	defer func() { p.panicking = false }()
+
+	for !p.currentToken.Is(lexer.EOF) && p.depth >= depth {
+		if p.depth == depth {
+			if p.currentToken.Is(lexer.SEMICOLON) {
+				return
+			}
+
+			if p.currentToken.Is(lexer.RBRACE) && !p.peekToken.Is(lexer.ELSE) {
+				// Take the semicolon too if the brace closed a literal, e.g. let h = {...};
+				if p.peekToken.Is(lexer.SEMICOLON) {
+					p.nextToken()
+				}
+				return
+			}
+
+			if p.peekToken.Is(lexer.RBRACE) && !p.currentToken.Is(lexer.LBRACE) {
+				return
+			}
+		}
+
+		p.nextToken()
 	}
 }
 
@@ -159,6 +278,12 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// Let the function know its own name so the compiler can
+	// support recursion inside closures
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekToken.Is(lexer.SEMICOLON) {
 		p.nextToken()
 	}
@@ -180,6 +305,109 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.currentToken}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekToken.Is(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.currentToken}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(lexer.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekToken.Is(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseLoopBody parses the block of a while or for loop, inside which
+// break and continue are allowed
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.currentToken}
+
+	if p.loops == 0 {
+		p.addError("", p.currentToken, "break outside of a loop")
+		return nil
+	}
+
+	if p.peekToken.Is(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.currentToken}
+
+	if p.loops == 0 {
+		p.addError("", p.currentToken, "continue outside of a loop")
+		return nil
+	}
+
+	if p.peekToken.Is(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.currentToken}
 
@@ -200,7 +428,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	leftExp := prefix()
 
-	for !p.peekToken.Is(lexer.SEMICOLON) && precedence < p.peekPrecedence() {
+	for !p.panicking && !p.peekToken.Is(lexer.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -230,6 +458,12 @@ func (p *Parser) currentPrecedence() int {
 	return LOWEST
 }
 
+// parseIllegal reports the problem the lexer found, there is nothing to parse
+func (p *Parser) parseIllegal() ast.Expression {
+	p.addError("", p.currentToken, "%s", p.currentToken.Literal)
+	return nil
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 }
@@ -238,9 +472,29 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 
 	value, err := strconv.Atoi(p.currentToken.Literal)
+	if err == nil {
+		lit.Value = value
+		return lit
+	}
+
+	// Too big for an int, the lexer guarantees it's all digits
+	bigValue, ok := new(big.Int).SetString(p.currentToken.Literal, 10)
+	if !ok {
+		p.addError("", p.currentToken, "could not parse %q as integer", p.currentToken.Literal)
+		return nil
+	}
+
+	lit.Big = bigValue
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.currentToken}
+
+	value, err := strconv.ParseFloat(p.currentToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError("", p.currentToken, "could not parse %q as float", p.currentToken.Literal)
 		return nil
 	}
 
@@ -276,6 +530,28 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.currentToken,
+		Operator: p.currentToken.Literal,
+		Target:   target,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError("", p.currentToken, "cannot assign to %s", target.String())
+		return nil
+	}
+
+	// Parsing the value at the lowest precedence makes assignment right associative
+	// so 'x = y = 5' assigns 5 to y and then to x
+	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.currentToken, Value: p.currentToken.Is(lexer.TRUE)}
 }
@@ -388,6 +664,18 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekToken.Is(lexer.ELSE) {
 		p.nextToken()
 
+		if p.peekToken.Is(lexer.IF) {
+			p.nextToken()
+
+			elseIf, ok := p.parseIfExpression().(*ast.IfExpression)
+			if !ok {
+				return nil
+			}
+
+			expression.ElseIf = elseIf
+			return expression
+		}
+
 		if !p.expectPeek(lexer.LBRACE) {
 			return nil
 		}
@@ -402,11 +690,21 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.currentToken}
 	block.Statements = []ast.Statement{}
 
+	// The closing brace will be at the same depth as the opening one
+	depth := p.depth
+
 	p.nextToken()
 
 	for !p.currentToken.Is(lexer.RBRACE) && !p.currentToken.Is(lexer.EOF) {
 		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+
+		// A broken statement may have run into our closing brace while recovering
+		if p.currentToken.Is(lexer.RBRACE) && p.depth == depth {
+			break
+		}
 
 		p.nextToken()
 	}
@@ -431,7 +729,11 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	// A function body can't break out of a loop the function is defined in
+	loops := p.loops
+	p.loops = 0
 	lit.Body = p.parseBlockStatement()
+	p.loops = loops
 
 	return lit
 }
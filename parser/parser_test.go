@@ -140,6 +140,63 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "1180591620717411303424;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Big == nil || literal.Big.String() != "1180591620717411303424" {
+		t.Errorf("literal.Big not 1180591620717411303424. got=%s", literal.Big)
+	}
+	if literal.String() != "1180591620717411303424" {
+		t.Errorf("literal.String() not 1180591620717411303424. got=%s", literal.String())
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "2.5;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 2.5 {
+		t.Errorf("literal.Value not %f. got=%f", 2.5, literal.Value)
+	}
+	if literal.TokenLiteral() != "2.5" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "2.5",
+			literal.TokenLiteral())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -246,6 +303,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"a + b % c <= d >= e",
+			"(((a + (b % c)) <= d) >= e)",
+		},
+		{
+			"a <= b == b >= a",
+			"((a <= b) == (b >= a))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && c == d || !e",
+			"(((a < b) && (c == d)) || (!e))",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -365,6 +446,90 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   string
+		operator string
+		want     string
+	}{
+		{"x = 5;", "x", "=", "x = 5"},
+		{"x += 1 + 2;", "x", "+=", "x += (1 + 2)"},
+		{"x -= y * 2;", "x", "-=", "x -= (y * 2)"},
+		{"x *= -1;", "x", "*=", "x *= (-1)"},
+		{"x /= 2;", "x", "/=", "x /= 2"},
+		{"x = y = 5;", "x", "=", "x = y = 5"},
+		{"x = a || b;", "x", "=", "x = (a || b)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("wrong number of statements, got %d, wanted %d", len(program.Statements), 1)
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("statement was not an ExpressionStatement, got %T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("expression is not an AssignExpression, got %T", stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Target, tt.target) {
+			return
+		}
+
+		if exp.Operator != tt.operator {
+			t.Errorf("wrong operator: got %q, wanted %q", exp.Operator, tt.operator)
+		}
+
+		if exp.String() != tt.want {
+			t.Errorf("wrong string: got %q, wanted %q", exp.String(), tt.want)
+		}
+	}
+
+	indexTests := []struct {
+		input string
+		want  string
+	}{
+		{"arr[0] = 1;", "(arr[0]) = 1"},
+		{`h["a"] += x * 2;`, `(h[a]) += (x * 2)`},
+		{"m[i][j] = 0;", "((m[i])[j]) = 0"},
+	}
+
+	for _, tt := range indexTests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("%s: expression is not an AssignExpression", tt.input)
+		}
+
+		if _, ok := exp.Target.(*ast.IndexExpression); !ok {
+			t.Errorf("%s: target is not an IndexExpression, got %T", tt.input, exp.Target)
+		}
+
+		if exp.String() != tt.want {
+			t.Errorf("wrong string: got %q, wanted %q", exp.String(), tt.want)
+		}
+	}
+
+	// Assignment is right associative
+	program := New(lexer.New("x = y = 5;")).ParseProgram()
+	outer := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignExpression)
+	if _, ok := outer.Value.(*ast.AssignExpression); !ok {
+		t.Errorf("x = y = 5 did not assign y = 5 to x, value was %T", outer.Value)
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -501,6 +666,155 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("wrong number of statements, got %d, wanted %d", len(program.Statements), 1)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("statement was not a WhileStatement, got %T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("wrong number of body statements, got %d, wanted %d", len(stmt.Body.Statements), 1)
+	}
+
+	body, ok := stmt.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("body statement not an ExpressionStatement, got %T", stmt.Body.Statements[0])
+	}
+
+	if !testIdentifier(t, body.Expression, "x") {
+		return
+	}
+
+	if stmt.String() != "while(x < 10) x" {
+		t.Errorf("wrong string: got %q, wanted %q", stmt.String(), "while(x < 10) x")
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in [1, 2]) { x; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("wrong number of statements, got %d, wanted %d", len(program.Statements), 1)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("statement was not a ForStatement, got %T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+
+	array, ok := stmt.Iterable.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("iterable is not an ArrayLiteral, got %T", stmt.Iterable)
+	}
+
+	if len(array.Elements) != 2 {
+		t.Fatalf("wrong number of elements, got %d, wanted %d", len(array.Elements), 2)
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("wrong number of body statements, got %d, wanted %d", len(stmt.Body.Statements), 1)
+	}
+
+	if stmt.String() != "for (x in [1, 2]) x" {
+		t.Errorf("wrong string: got %q, wanted %q", stmt.String(), "for (x in [1, 2]) x")
+	}
+}
+
+func TestBreakAndContinue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"while (true) { break; }", "whiletrue break;"},
+		{"for (x in y) { if (x) { continue } }", "for (x in y) ifx continue;"},
+		{"while (a) { for (x in y) { break; }; continue; }", "whilea for (x in y) break;continue;"},
+		{"for (x in y) { let f = fn() { while (true) { break; } }; }", "for (x in y) let f = fn()whiletrue break;;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.want {
+			t.Errorf("%s: wrong program: got %q, wanted %q", tt.input, program.String(), tt.want)
+		}
+	}
+}
+
+func TestIfElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("wrong number of statements, got %d, wanted %d", len(program.Statements), 1)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement was not an ExpressionStatement, got %T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("expression is not an IfExpression, got %T", stmt.Expression)
+	}
+
+	if exp.Alternative != nil {
+		t.Errorf("alternative should be on the else if, got %+v", exp.Alternative)
+	}
+
+	elseIf := exp.ElseIf
+	if elseIf == nil {
+		t.Fatalf("else if was nil")
+	}
+
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+
+	if elseIf.ElseIf != nil {
+		t.Errorf("else if should end the chain, got %+v", elseIf.ElseIf)
+	}
+
+	if elseIf.Alternative == nil || len(elseIf.Alternative.Statements) != 1 {
+		t.Fatalf("else if has wrong alternative, got %+v", elseIf.Alternative)
+	}
+
+	want := "if(x < y) xelse if(x > y) yelse z"
+	if exp.String() != want {
+		t.Errorf("wrong string: got %q, wanted %q", exp.String(), want)
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -932,8 +1246,200 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 
 	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	for _, err := range errors {
+		t.Errorf("parser error: %q", err)
 	}
 	t.FailNow()
 }
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     string
+		expected lexer.TokenType
+		actual   lexer.TokenType
+		literal  string
+	}{
+		{"let = 5;", "test.monkey:1:5: expected next token to be IDENT, got = instead", lexer.IDENT, lexer.ASSIGN, "="},
+		{"let x = 5;\nlet y 10;", "test.monkey:2:7: expected next token to be =, got INT instead", lexer.ASSIGN, lexer.INT, "10"},
+		{"let x = 5;\n  >;", "test.monkey:2:3: no prefix parse function for > found", "", lexer.GT, ">"},
+		{"for (x 5) { x }", "test.monkey:1:8: expected next token to be IN, got INT instead", lexer.IN, lexer.INT, "5"},
+		{"while x { x }", "test.monkey:1:7: expected next token to be (, got IDENT instead", lexer.LPAREN, lexer.IDENT, "x"},
+		{"break;", "test.monkey:1:1: break outside of a loop", "", lexer.BREAK, "break"},
+		{"5 = 1;", "test.monkey:1:3: cannot assign to 5", "", lexer.ASSIGN, "="},
+		{"x + 1 += 2;", "test.monkey:1:7: cannot assign to (x + 1)", "", lexer.PLUSASSIGN, "+="},
+		{"f(x) = 2;", "test.monkey:1:6: cannot assign to f(x)", "", lexer.ASSIGN, "="},
+		{"if (a) { 1 } else if b { 2 }", "test.monkey:1:22: expected next token to be (, got IDENT instead", lexer.LPAREN, lexer.IDENT, "b"},
+		{"if (x) { continue; }", "test.monkey:1:10: continue outside of a loop", "", lexer.CONTINUE, "continue"},
+		{"while (x) { let f = fn() { break; }; }", "test.monkey:1:28: break outside of a loop", "", lexer.BREAK, "break"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.NewWithFilename("test.monkey", tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q but got none", tt.input)
+		}
+
+		err := errors[0]
+		if err.Error() != tt.want {
+			t.Errorf("wrong parser error: got %q, wanted %q", err, tt.want)
+		}
+
+		if err.Pos != err.Actual.Pos {
+			t.Errorf("error not reported at the actual token: got %s, wanted %s", err.Pos, err.Actual.Pos)
+		}
+
+		if err.Expected != tt.expected {
+			t.Errorf("wrong expected token: got %q, wanted %q", err.Expected, tt.expected)
+		}
+
+		if err.Actual.Type != tt.actual {
+			t.Errorf("wrong actual token type: got %q, wanted %q", err.Actual.Type, tt.actual)
+		}
+
+		if err.Actual.Literal != tt.literal {
+			t.Errorf("wrong actual token literal: got %q, wanted %q", err.Actual.Literal, tt.literal)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	p := New(lexer.New("let x 5;"))
+	p.ParseProgram()
+
+	if err := (ErrorList{}).Err(); err != nil {
+		t.Errorf("empty ErrorList.Err() should be nil, got %v", err)
+	}
+
+	err := p.Errors().Err()
+	if err == nil {
+		t.Fatalf("expected an error from Err()")
+	}
+
+	want := "1:7: expected next token to be =, got INT instead"
+	if err.Error() != want {
+		t.Errorf("wrong error: got %q, wanted %q", err, want)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		errors  []string
+		program string // What survives once the broken statements are dropped
+	}{
+		{
+			name:    "independent statements",
+			input:   "let = 5;\nlet y 10;\nlet z = 3;\n>;\nz",
+			errors:  []string{"1:5: expected next token to be IDENT, got = instead", "2:7: expected next token to be =, got INT instead", "4:1: no prefix parse function for > found"},
+			program: "let z = 3;z",
+		},
+		{
+			name:    "unclosed paren",
+			input:   "let x = (1 + 2;\nlet y = ;\ny",
+			errors:  []string{"1:15: expected next token to be ), got ; instead", "2:9: no prefix parse function for ; found"},
+			program: "y",
+		},
+		{
+			name:    "inside a function body",
+			input:   "let f = fn(a) {\n  let = a;\n  a + ;\n  a\n};\nlet g = ;\nf(1)",
+			errors:  []string{"2:7: expected next token to be IDENT, got = instead", "3:7: no prefix parse function for ; found", "6:9: no prefix parse function for ; found"},
+			program: "let f = fn(a)a;f(1)",
+		},
+		{
+			name:    "runs into the closing brace",
+			input:   "fn(x) { x + }(1); let = 1",
+			errors:  []string{"1:13: no prefix parse function for } found", "1:23: expected next token to be IDENT, got = instead"},
+			program: "fn(x)(1)",
+		},
+		{
+			name:    "stray closing brace",
+			input:   "if (x) { let = 1 }\nlet ok = 1;\n}\nlet z = ;",
+			errors:  []string{"1:14: expected next token to be IDENT, got = instead", "3:1: no prefix parse function for } found", "4:9: no prefix parse function for ; found"},
+			program: "ifx let ok = 1;",
+		},
+		{
+			name:    "inside literals",
+			input:   "let h = {1: 2, 3 4};\nlet a = [1, 2;\nlet b = 1;",
+			errors:  []string{"1:18: expected next token to be :, got INT instead", "2:14: expected next token to be ], got ; instead"},
+			program: "let b = 1;",
+		},
+		{
+			name:    "if with else branches",
+			input:   "if (x { 1 } else { 2 }\nif (x { 1 } else if (y) { 2 } else { 3 };\nlet z = 1;",
+			errors:  []string{"1:7: expected next token to be ), got { instead", "2:7: expected next token to be ), got { instead"},
+			program: "let z = 1;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			program := p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) != len(tt.errors) {
+				t.Fatalf("wrong number of errors: got %d, wanted %d\n%s", len(errors), len(tt.errors), errors)
+			}
+
+			for i, err := range errors {
+				if err.Error() != tt.errors[i] {
+					t.Errorf("wrong error %d: got %q, wanted %q", i, err, tt.errors[i])
+				}
+			}
+
+			if program.String() != tt.program {
+				t.Errorf("wrong program: got %q, wanted %q", program.String(), tt.program)
+			}
+		})
+	}
+}
+
+func TestCommentsAreIgnored(t *testing.T) {
+	input := `// add two numbers
+let add = fn(a, b) { /* the sum */ a + b }; // done
+add(1, /* two */ 2)`
+
+	for _, emit := range []bool{false, true} {
+		l := lexer.New(input)
+		l.EmitComments(emit)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		want := "let add = fn(a, b)(a + b);add(1, 2)"
+		if program.String() != want {
+			t.Errorf("wrong program with EmitComments(%t): got %q, wanted %q", emit, program.String(), want)
+		}
+	}
+}
+
+func TestIllegalTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let x = #;", "1:9: unexpected character '#'"},
+		{"let x = 1; /* oops", "1:12: unterminated block comment"},
+		{`let s = "bad \q";`, `1:9: unknown escape sequence \q`},
+		{`let s = "oops`, "1:9: unterminated string"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q: got %d, wanted 1\n%s", tt.input, len(errors), errors)
+		}
+
+		if errors[0].Error() != tt.want {
+			t.Errorf("wrong error: got %q, wanted %q", errors[0], tt.want)
+		}
+	}
+}
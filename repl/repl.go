@@ -5,19 +5,59 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/FollowTheProcess/monkey/ast"
 	"github.com/FollowTheProcess/monkey/compiler"
+	"github.com/FollowTheProcess/monkey/eval"
 	"github.com/FollowTheProcess/monkey/lexer"
+	"github.com/FollowTheProcess/monkey/object"
 	"github.com/FollowTheProcess/monkey/parser"
 	"github.com/FollowTheProcess/monkey/vm"
 )
 
 const PROMPT = ">> "
 
+// filename is what positions in REPL errors are reported against
+const filename = "<repl>"
+
+// Engine is the backend used to execute Monkey code
+type Engine string
+
+const (
+	EngineEval Engine = "eval" // The tree-walking evaluator
+	EngineVM   Engine = "vm"   // The bytecode compiler and virtual machine
+)
+
+// Options configures a REPL session
+type Options struct {
+	Engine  Engine // Which backend to run code with, defaults to EngineVM
+	Checked bool   // Report integer overflow as an error rather than promoting to a big integer
+}
+
+// ParseEngine converts a user supplied engine name to an Engine
+func ParseEngine(name string) (Engine, error) {
+	switch engine := Engine(name); engine {
+	case EngineEval, EngineVM:
+		return engine, nil
+	default:
+		return "", fmt.Errorf("unknown engine %q, expected %q or %q", name, EngineEval, EngineVM)
+	}
+}
+
 // Start will start a REPL, currently only exited with ctrl + c
-func Start(in io.Reader, out io.Writer) {
+func Start(in io.Reader, out io.Writer, options Options) {
 	scanner := bufio.NewScanner(in)
 
+	// The evaluator keeps its environment for the whole session
+	env := object.NewEnvironment()
+	env.SetCheckedArithmetic(options.Checked)
+
+	// As does the VM, by way of the compiler's symbol table and constants
+	// and the VM's globals
+	state := newVMState(options.Checked)
+
 	for {
 		fmt.Fprint(out, PROMPT)
 		scanned := scanner.Scan()
@@ -26,37 +66,127 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.NewWithFilename(filename, line)
 		p := parser.New(l)
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+			printParseErrors(out, line, p.Errors())
 			continue
 		}
 
-		// Here's the new bit, add the compiler into the mix!
-		comp := compiler.New()
-		err := comp.Compile(program)
-		if err != nil {
-			fmt.Fprintf(out, "Uh oh! This doesn't compile:\n %s\n", err)
-			continue
+		switch options.Engine {
+		case EngineEval:
+			runEval(out, program, env)
+		default:
+			runVM(out, program, state)
 		}
+	}
+}
 
-		machine := vm.New(comp.ByteCode())
-		err = machine.Run()
-		if err != nil {
-			fmt.Fprintf(out, "Uh oh! Can't execute the bytecode:\n %s\n", err)
-			continue
+func runEval(out io.Writer, program *ast.Program, env *object.Environment) {
+	evaluated := eval.Eval(program, env)
+	if evaluated != nil {
+		fmt.Fprintln(out, evaluated.Inspect())
+	}
+}
+
+// vmState is everything the compiler and VM need to keep
+// between lines so earlier bindings stay visible
+type vmState struct {
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+	checked     bool
+}
+
+func newVMState(checked bool) *vmState {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	return &vmState{
+		symbolTable: symbolTable,
+		constants:   []object.Object{},
+		globals:     make([]object.Object, vm.GlobalsSize),
+		checked:     checked,
+	}
+}
+
+func runVM(out io.Writer, program *ast.Program, state *vmState) {
+	comp := compiler.NewWithState(state.symbolTable, state.constants)
+	err := comp.Compile(program)
+	if err != nil {
+		fmt.Fprintf(out, "Uh oh! This doesn't compile:\n %s\n", err)
+		return
+	}
+
+	bytecode := comp.ByteCode()
+	state.constants = bytecode.Constants
+
+	machine := vm.NewWithGlobalsStore(bytecode, state.globals)
+	machine.SetCheckedArithmetic(state.checked)
+	err = machine.Run()
+	if err != nil {
+		fmt.Fprintf(out, "Uh oh! Can't execute the bytecode:\n %s\n", err)
+		return
+	}
+
+	lastPopped := machine.LastPoppedStackElem()
+	if lastPopped != nil {
+		fmt.Fprintln(out, lastPopped.Inspect())
+	}
+}
+
+// printParseErrors prints each error followed by the line of source it was found on
+// with a caret under the offending token
+func printParseErrors(out io.Writer, source string, errors parser.ErrorList) {
+	for _, err := range errors {
+		fmt.Fprintf(out, "\t%s\n", err)
+
+		width := utf8.RuneCountInString(err.Actual.Literal)
+		if err.Actual.Is(lexer.ILLEGAL) {
+			// The literal is a description of the problem, not source code
+			width = 1
 		}
 
-		stackTop := machine.StackTop()
-		fmt.Fprintln(out, stackTop.Inspect())
+		line, caret, ok := excerpt(source, err.Pos, width)
+		if ok {
+			fmt.Fprintf(out, "\t%s\n\t%s\n", line, caret)
+		}
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		fmt.Fprintf(out, "\t%s\n", msg)
+// excerpt returns the line of source that pos is on, and a line of carets width
+// characters wide that lines up with pos underneath it
+func excerpt(source string, pos lexer.Position, width int) (line, caret string, ok bool) {
+	lines := strings.Split(source, "\n")
+	if !pos.IsValid() || pos.Line > len(lines) {
+		return "", "", false
+	}
+
+	line = strings.TrimRight(lines[pos.Line-1], "\r")
+
+	// Copy tabs so the caret lines up however the terminal renders them
+	var b strings.Builder
+	column := 1
+	for _, char := range line {
+		if column >= pos.Column {
+			break
+		}
+		if char == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		column++
 	}
+
+	if width < 1 {
+		width = 1
+	}
+	b.WriteString(strings.Repeat("^", width))
+
+	return line, b.String(), true
 }
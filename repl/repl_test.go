@@ -0,0 +1,163 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/FollowTheProcess/monkey/lexer"
+)
+
+func TestParseEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Engine
+		wantErr bool
+	}{
+		{"eval", EngineEval, false},
+		{"vm", EngineVM, false},
+		{"jit", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEngine(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEngine(%q) returned wrong error: got %v, wanted error: %t", tt.name, err, tt.wantErr)
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseEngine(%q) wrong engine: got %q, wanted %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStartEngines(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		input  string
+		want   string
+	}{
+		{EngineEval, "1 + 2\n", ">> 3\n>> "},
+		{EngineVM, "1 + 2\n", ">> 3\n>> "},
+		{EngineEval, "5 + true\n", ">> ERROR: <repl>:1:3: type mismatch: INTEGER + BOOLEAN\n>> "},
+		{EngineVM, "5 + true\n", ">> Uh oh! Can't execute the bytecode:\n <repl>:1:3: type mismatch: INTEGER + BOOLEAN\n>> "},
+	}
+
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		Start(strings.NewReader(tt.input), out, Options{Engine: tt.engine})
+
+		if out.String() != tt.want {
+			t.Errorf("wrong %s output for %q: got %q, wanted %q", tt.engine, tt.input, out.String(), tt.want)
+		}
+	}
+}
+
+func TestCheckedOption(t *testing.T) {
+	input := "9223372036854775807 + 1\n"
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		out := &bytes.Buffer{}
+		Start(strings.NewReader(input), out, Options{Engine: engine, Checked: true})
+
+		if !strings.Contains(out.String(), "<repl>:1:21: integer overflow: 9223372036854775807 + 1") {
+			t.Errorf("%s engine did not report overflow, got output %q", engine, out.String())
+		}
+
+		out.Reset()
+		Start(strings.NewReader(input), out, Options{Engine: engine})
+
+		if !strings.Contains(out.String(), ">> 9223372036854775808") {
+			t.Errorf("%s engine should promote to a big integer when unchecked, got output %q", engine, out.String())
+		}
+	}
+}
+
+func TestStatePersistsAcrossLines(t *testing.T) {
+	input := `let x = 5;
+let double = fn(a) { a * 2 };
+double(x)
+let y = double(x) + x;
+y
+`
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		out := &bytes.Buffer{}
+		Start(strings.NewReader(input), out, Options{Engine: engine})
+
+		if !strings.HasSuffix(out.String(), "15\n"+PROMPT) {
+			t.Errorf("%s engine lost state between lines, got output %q", engine, out.String())
+		}
+	}
+}
+
+func TestNothingToPrint(t *testing.T) {
+	input := "\n// just a comment\n/* and another */\nfor (x in [1]) { x }\nwhile (false) {}\nlet z = 1;\n"
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		out := &bytes.Buffer{}
+		Start(strings.NewReader(input), out, Options{Engine: engine})
+
+		want := strings.Repeat(PROMPT, 7)
+		if out.String() != want {
+			t.Errorf("%s engine printed something for empty lines: got %q, wanted %q", engine, out.String(), want)
+		}
+	}
+}
+
+func TestFailedCompileDoesNotCrash(t *testing.T) {
+	input := "let a = 1; b;\na\n"
+
+	out := &bytes.Buffer{}
+	Start(strings.NewReader(input), out, Options{Engine: EngineVM})
+
+	if !strings.Contains(out.String(), "identifier not found: b") {
+		t.Errorf("expected compile error for b, got %q", out.String())
+	}
+
+	if !strings.Contains(out.String(), "identifier not found: a") {
+		t.Errorf("expected runtime error for a, got %q", out.String())
+	}
+}
+
+func TestParseErrorExcerpt(t *testing.T) {
+	input := "let x 10;\n"
+	want := ">> \t<repl>:1:7: expected next token to be =, got INT instead\n" +
+		"\tlet x 10;\n" +
+		"\t      ^^\n" +
+		">> "
+
+	out := &bytes.Buffer{}
+	Start(strings.NewReader(input), out, Options{Engine: EngineEval})
+
+	if out.String() != want {
+		t.Errorf("wrong parse error output: got %q, wanted %q", out.String(), want)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		source    string
+		pos       lexer.Position
+		width     int
+		wantLine  string
+		wantCaret string
+		wantOK    bool
+	}{
+		{"let x 10;", lexer.Position{Line: 1, Column: 7}, 2, "let x 10;", "      ^^", true},
+		{"\tlet = 1", lexer.Position{Line: 1, Column: 6}, 1, "\tlet = 1", "\t    ^", true},
+		{"let ü = é", lexer.Position{Line: 1, Column: 9}, 1, "let ü = é", "        ^", true},
+		{"a\nlet (", lexer.Position{Line: 2, Column: 6}, 0, "let (", "     ^", true},
+		{"a", lexer.Position{Line: 2, Column: 1}, 1, "", "", false},
+		{"a", lexer.Position{}, 1, "", "", false},
+	}
+
+	for _, tt := range tests {
+		line, caret, ok := excerpt(tt.source, tt.pos, tt.width)
+		if ok != tt.wantOK || line != tt.wantLine || caret != tt.wantCaret {
+			t.Errorf("excerpt(%q, %s, %d): got (%q, %q, %t), wanted (%q, %q, %t)",
+				tt.source, tt.pos, tt.width, line, caret, ok, tt.wantLine, tt.wantCaret, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package vm
+
+import (
+	"github.com/FollowTheProcess/monkey/object"
+)
+
+// cell holds a local variable that closures capture and that is assigned to,
+// the function defining it and every closure capturing it share the one cell
+// so they all see the latest value, like the evaluator's environments
+//
+// Like iterator it is never visible to Monkey code
+type cell struct {
+	name  string        // Of the local it holds, for errors
+	value object.Object // nil until the local is first assigned
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string         { return c.value.Inspect() }
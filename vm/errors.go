@@ -0,0 +1,27 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/FollowTheProcess/monkey/lexer"
+)
+
+// RuntimeError is an error raised while executing bytecode, it carries the
+// position of the source code that the failing instruction was compiled from
+type RuntimeError struct {
+	Pos     lexer.Position // Invalid if the bytecode had no source map
+	Message string
+}
+
+func newRuntimeError(pos lexer.Position, err error) *RuntimeError {
+	return &RuntimeError{Pos: pos, Message: err.Error()}
+}
+
+// Error satisfies the error interface, formatted as file:line:col: message
+func (e *RuntimeError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
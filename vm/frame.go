@@ -0,0 +1,23 @@
+package vm
+
+import (
+	"github.com/FollowTheProcess/monkey/code"
+	"github.com/FollowTheProcess/monkey/object"
+)
+
+// Frame is a call frame, it holds the execution state of a single function call
+type Frame struct {
+	cl          *object.Closure
+	ip          int // The instruction pointer within this frame
+	basePointer int // The stack pointer before the call, locals live just above it
+}
+
+// NewFrame creates a Frame ready to execute the closure cl
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+// Instructions returns the bytecode of the function in this frame
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
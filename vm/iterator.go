@@ -0,0 +1,29 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/FollowTheProcess/monkey/object"
+)
+
+// iterator is what a for loop keeps on the stack while it runs, it is never
+// visible to Monkey code
+type iterator struct {
+	elements []object.Object
+	index    int // Of the next element to hand out
+}
+
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
+func (it *iterator) Inspect() string         { return fmt.Sprintf("Iterator[%p]", it) }
+
+// next returns the next element, ok is false once they've all been handed out
+func (it *iterator) next() (element object.Object, ok bool) {
+	if it.index >= len(it.elements) {
+		return nil, false
+	}
+
+	element = it.elements[it.index]
+	it.index++
+
+	return element, true
+}
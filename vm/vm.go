@@ -1,36 +1,87 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
+	"math"
 
 	"github.com/FollowTheProcess/monkey/code"
 	"github.com/FollowTheProcess/monkey/compiler"
 	"github.com/FollowTheProcess/monkey/object"
 )
 
-const StackSize = 2048
+const (
+	StackSize   = 2048
+	GlobalsSize = 65536 // The max operand width of OpSetGlobal is 2 bytes
+	MaxFrames   = 1024
+)
+
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
 
 type VM struct {
-	constants    []object.Object
-	instructions code.Instructions
+	constants []object.Object
 
 	// The canonical stack for the VM
 	stack []object.Object
 	// The stack pointer, always points to the next value
 	// top of stack is stack[sp - 1]
 	sp int
+
+	globals     []object.Object
+	globalNames []string // For errors, see compiler.ByteCode
+
+	frames      []*Frame
+	framesIndex int // Always points to the next free frame
+
+	checked bool // Whether integer overflow is an error, see SetCheckedArithmetic
+
+	hasResult bool // Whether the program ends in an expression, see compiler.ByteCode
+	halted    bool // Whether the program ended early with a top level return
 }
 
 func New(bytecode *compiler.ByteCode) *VM {
+	// The top level program is run as if it were the body of a function
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, SourceMap: bytecode.SourceMap}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
 	return &VM{
-		instructions: bytecode.Instructions,
-		constants:    bytecode.Constants,
+		constants: bytecode.Constants,
 
 		stack: make([]object.Object, StackSize),
 		sp:    0,
+
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+
+		frames:      frames,
+		framesIndex: 1,
+
+		hasResult: bytecode.HasResult,
 	}
 }
 
+// NewWithGlobalsStore creates a VM that uses s as its globals store
+// so that global bindings persist across runs e.g. between lines in the REPL
+func NewWithGlobalsStore(bytecode *compiler.ByteCode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+// SetCheckedArithmetic turns on (or off) reporting integer arithmetic whose result
+// doesn't fit in an int as an error, by default it is promoted to a big integer
+func (vm *VM) SetCheckedArithmetic(checked bool) {
+	vm.checked = checked
+}
+
 func (vm *VM) StackTop() object.Object {
 	if vm.sp == 0 {
 		return nil
@@ -38,37 +89,721 @@ func (vm *VM) StackTop() object.Object {
 	return vm.stack[vm.sp-1]
 }
 
+// LastPoppedStackElem returns the element most recently popped off the stack
+// which after running a program is the value of its final expression statement,
+// or of a top level return. It is nil if the program ends in any other statement
+// e.g. a let or a loop, whose leftovers are not a value
+func (vm *VM) LastPoppedStackElem() object.Object {
+	if !vm.hasResult && !vm.halted {
+		return nil
+	}
+
+	return vm.stack[vm.sp]
+}
+
 func (vm *VM) Run() error {
-	for i := 0; i < len(vm.instructions); i++ {
-		op := code.Opcode(vm.instructions[i])
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		frame := vm.currentFrame()
+		ip := frame.ip
+		ins := frame.Instructions()
+		op := code.Opcode(ins[ip])
+
+		err := vm.execute(op, ins, ip)
+		if err != nil {
+			return newRuntimeError(frame.cl.Fn.SourceMap[ip], err)
+		}
+	}
+
+	return nil
+}
+
+// execute runs the single instruction op found at ins[ip]
+func (vm *VM) execute(op code.Opcode, ins code.Instructions, ip int) error {
+	switch op {
+	case code.OpConstant:
+		constIndex := code.ReadUint16(ins[ip+1:])
+		vm.currentFrame().ip += 2
+
+		err := vm.push(vm.constants[constIndex])
+		if err != nil {
+			return err
+		}
+
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan,
+		code.OpGreaterThanOrEqual, code.OpMod, code.OpLessThan,
+		code.OpLessThanOrEqual:
+		err := vm.executeBinaryOperation(op)
+		if err != nil {
+			return err
+		}
+
+	case code.OpBang:
+		err := vm.executeBangOperator()
+		if err != nil {
+			return err
+		}
+
+	case code.OpMinus:
+		err := vm.executeMinusOperator()
+		if err != nil {
+			return err
+		}
+
+	case code.OpTrue:
+		err := vm.push(True)
+		if err != nil {
+			return err
+		}
+
+	case code.OpFalse:
+		err := vm.push(False)
+		if err != nil {
+			return err
+		}
+
+	case code.OpNull:
+		err := vm.push(Null)
+		if err != nil {
+			return err
+		}
+
+	case code.OpJump:
+		position := int(code.ReadUint16(ins[ip+1:]))
+		// The loop increments ip, so land just before the target
+		vm.currentFrame().ip = position - 1
+
+	case code.OpJumpNotTruthy:
+		position := int(code.ReadUint16(ins[ip+1:]))
+		vm.currentFrame().ip += 2
+
+		condition := vm.pop()
+		if !isTruthy(condition) {
+			vm.currentFrame().ip = position - 1
+		}
+
+	case code.OpIter:
+		iterable := vm.pop()
+
+		elements, ok := object.Iterate(iterable)
+		if !ok {
+			return fmt.Errorf("cannot iterate over %s", iterable.Type())
+		}
+
+		err := vm.push(&iterator{elements: elements})
+		if err != nil {
+			return err
+		}
+
+	case code.OpIterNext:
+		position := int(code.ReadUint16(ins[ip+1:]))
+		vm.currentFrame().ip += 2
+
+		it := vm.StackTop().(*iterator)
+		element, ok := it.next()
+		if !ok {
+			vm.pop()
+			vm.currentFrame().ip = position - 1
+			return nil
+		}
+
+		err := vm.push(element)
+		if err != nil {
+			return err
+		}
+
+	case code.OpSetGlobal:
+		globalIndex := code.ReadUint16(ins[ip+1:])
+		vm.currentFrame().ip += 2
+
+		vm.globals[globalIndex] = vm.pop()
+
+	case code.OpGetGlobal:
+		globalIndex := code.ReadUint16(ins[ip+1:])
+		vm.currentFrame().ip += 2
+
+		global := vm.globals[globalIndex]
+		// A global is defined when its let is compiled, but only assigned if the let
+		// runs e.g. not in 'if (false) { let y = 1; }; y' or after a failed compile
+		// in the REPL. The evaluator has no binding at all in either case
+		if global == nil {
+			return fmt.Errorf("identifier not found: %s", vm.globalNames[globalIndex])
+		}
+
+		err := vm.push(global)
+		if err != nil {
+			return err
+		}
 
-		switch op {
-		case code.OpConstant:
-			constIndex := code.ReadUint16(vm.instructions[i+1:])
-			i += 2
-			err := vm.push(vm.constants[constIndex])
-			if err != nil {
-				return err
-			}
+	case code.OpSetLocal:
+		localIndex := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
 
-		case code.OpAdd:
-			// Note: this assumes the right hand value was the last one
-			// to be pushed onto the stack
-			right := vm.pop()
-			left := vm.pop()
-			leftValue := left.(*object.Integer).Value
-			rightValue := right.(*object.Integer).Value
+		frame := vm.currentFrame()
+		vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
 
-			result := leftValue + rightValue
-			if err := vm.push(&object.Integer{Value: result}); err != nil {
-				return err
-			}
+	case code.OpGetLocal:
+		localIndex := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
+
+		frame := vm.currentFrame()
+		local := vm.stack[frame.basePointer+int(localIndex)]
+		if local == nil {
+			// Like globals, a local whose let hasn't run has no binding in the evaluator
+			return fmt.Errorf("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+		}
+
+		err := vm.push(local)
+		if err != nil {
+			return err
+		}
+
+	case code.OpCall:
+		numArgs := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
+
+		err := vm.executeCall(int(numArgs))
+		if err != nil {
+			return err
+		}
+
+	case code.OpGetBuiltin:
+		builtinIndex := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
+
+		definition := object.Builtins[builtinIndex]
+
+		err := vm.push(definition.Builtin)
+		if err != nil {
+			return err
+		}
+
+	case code.OpClosure:
+		constIndex := code.ReadUint16(ins[ip+1:])
+		numFree := code.ReadUint8(ins[ip+3:])
+		vm.currentFrame().ip += 3
+
+		err := vm.pushClosure(int(constIndex), int(numFree))
+		if err != nil {
+			return err
+		}
+
+	case code.OpGetFree:
+		freeIndex := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
+
+		currentClosure := vm.currentFrame().cl
+		err := vm.push(currentClosure.Free[freeIndex])
+		if err != nil {
+			return err
+		}
+
+	case code.OpCurrentClosure:
+		currentClosure := vm.currentFrame().cl
+		err := vm.push(currentClosure)
+		if err != nil {
+			return err
+		}
+
+	case code.OpReturnValue:
+		returnValue := vm.pop()
+
+		// Returning from the main frame ends the program, the popped
+		// value is left as the last popped element for the caller
+		if vm.framesIndex == 1 {
+			vm.halt()
+			return nil
+		}
+
+		frame := vm.popFrame()
+		// The extra -1 also discards the function being called
+		vm.sp = frame.basePointer - 1
+
+		err := vm.push(returnValue)
+		if err != nil {
+			return err
+		}
+
+	case code.OpReturn:
+		if vm.framesIndex == 1 {
+			vm.stack[vm.sp] = Null
+			vm.halt()
+			return nil
+		}
+
+		frame := vm.popFrame()
+		vm.sp = frame.basePointer - 1
+
+		err := vm.push(Null)
+		if err != nil {
+			return err
+		}
+
+	case code.OpArray:
+		numElements := int(code.ReadUint16(ins[ip+1:]))
+		vm.currentFrame().ip += 2
+
+		array := vm.buildArray(vm.sp-numElements, vm.sp)
+		vm.sp = vm.sp - numElements
+
+		err := vm.push(array)
+		if err != nil {
+			return err
+		}
+
+	case code.OpHash:
+		numElements := int(code.ReadUint16(ins[ip+1:]))
+		vm.currentFrame().ip += 2
+
+		hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+		if err != nil {
+			return err
+		}
+		vm.sp = vm.sp - numElements
+
+		err = vm.push(hash)
+		if err != nil {
+			return err
+		}
+
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+
+		err := vm.executeIndexExpression(left, index)
+		if err != nil {
+			return err
+		}
+
+	case code.OpSetIndex:
+		value := vm.pop()
+		index := vm.pop()
+		left := vm.pop()
+
+		err := vm.executeSetIndex(left, index, value)
+		if err != nil {
+			return err
+		}
+
+	case code.OpDupPair:
+		first, second := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+
+		err := vm.push(first)
+		if err != nil {
+			return err
+		}
+
+		err = vm.push(second)
+		if err != nil {
+			return err
+		}
+
+	case code.OpNewCell:
+		localIndex := code.ReadUint8(ins[ip+1:])
+		vm.currentFrame().ip++
+
+		frame := vm.currentFrame()
+		slot := frame.basePointer + int(localIndex)
+		vm.stack[slot] = &cell{name: frame.cl.Fn.LocalNames[localIndex], value: vm.stack[slot]}
+
+	case code.OpGetCell:
+		c := vm.pop().(*cell)
+		if c.value == nil {
+			return fmt.Errorf("identifier not found: %s", c.name)
+		}
+
+		err := vm.push(c.value)
+		if err != nil {
+			return err
 		}
+
+	case code.OpSetCell:
+		c := vm.pop().(*cell)
+		c.value = vm.pop()
+
+	case code.OpPop:
+		vm.pop()
 	}
 
 	return nil
 }
 
+// executeCall calls the function sitting below its numArgs arguments on the stack
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+// callClosure sets up a new frame for cl, whose arguments
+// are already on the stack
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: got %d, wanted %d", numArgs, cl.Fn.NumParameters)
+	}
+
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("too many nested calls: max %d frames", MaxFrames)
+	}
+
+	// The arguments are already on the stack and become the first locals
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("STACK OVERFLOW!!")
+	}
+
+	vm.pushFrame(frame)
+
+	// Reserve the rest of the local slots, clearing whatever an earlier
+	// call left there so reading a local before its let is an error
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	for i := frame.basePointer + numArgs; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+
+	return nil
+}
+
+// callBuiltin runs fn directly on the numArgs arguments on the stack
+//
+// Errors from builtins stop execution, just as they do in the evaluator
+func (vm *VM) callBuiltin(fn *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := fn.Fn(args...)
+	// Discard the arguments and the builtin itself
+	vm.sp = vm.sp - numArgs - 1
+
+	if errObj, ok := result.(*object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+
+	if result == nil {
+		return vm.push(Null)
+	}
+
+	return vm.push(result)
+}
+
+// pushClosure wraps the function constant at constIndex in a Closure,
+// capturing the numFree values on top of the stack
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// operatorSymbols maps the binary opcodes back to their source operator
+// so errors read the same as the evaluator's
+var operatorSymbols = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+
+	code.OpGreaterThanOrEqual: ">=",
+	code.OpLessThanOrEqual:    "<=",
+	code.OpMod:                "%",
+}
+
+// executeBinaryOperation pops two operands and applies op to them, the cases
+// mirror evalInfixExpression in the evaluator so the two backends agree
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	// Note: this assumes the right hand value was the last one
+	// to be pushed onto the stack
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case leftType == object.INTEGER && rightType == object.INTEGER:
+		return vm.executeBinaryIntegerOperation(op, left, right)
+
+	// Any other mix of numbers is done in floating point
+	case object.IsNumber(left) && object.IsNumber(right):
+		return vm.executeBinaryFloatOperation(op, left, right)
+
+	case leftType == object.STRING && rightType == object.STRING:
+		return vm.executeBinaryStringOperation(op, left, right)
+
+	// Booleans and Null are singletons so identity is equality
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+
+	case leftType != rightType:
+		return fmt.Errorf("type mismatch: %s %s %s", leftType, operatorSymbols[op], rightType)
+
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", leftType, operatorSymbols[op], rightType)
+	}
+}
+
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	leftInt := left.(*object.Integer)
+	rightInt := right.(*object.Integer)
+
+	switch op {
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod:
+		if op == code.OpDiv && rightInt.IsZero() {
+			return fmt.Errorf("division by zero: %s / %s", left.Inspect(), right.Inspect())
+		}
+
+		if op == code.OpMod && rightInt.IsZero() {
+			return fmt.Errorf("modulo by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+
+		result, overflow := object.IntegerArithmetic(operatorSymbols[op], leftInt, rightInt)
+		if vm.checked && overflow {
+			return fmt.Errorf("integer overflow: %s %s %s", left.Inspect(), operatorSymbols[op], right.Inspect())
+		}
+		return vm.push(result)
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) < 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) > 0))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) <= 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) >= 0))
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(object.CompareIntegers(leftInt, rightInt) != 0))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbols[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := object.ToFloat(left)
+	rightValue := object.ToFloat(right)
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.Float{Value: leftValue + rightValue})
+	case code.OpSub:
+		return vm.push(&object.Float{Value: leftValue - rightValue})
+	case code.OpMul:
+		return vm.push(&object.Float{Value: leftValue * rightValue})
+	case code.OpDiv:
+		return vm.push(&object.Float{Value: leftValue / rightValue})
+	case code.OpMod:
+		if rightValue == 0 {
+			return fmt.Errorf("modulo by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+		return vm.push(&object.Float{Value: math.Mod(leftValue, rightValue)})
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbols[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	if op != code.OpAdd {
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operatorSymbols[op], right.Type())
+	}
+
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	return vm.push(&object.String{Value: leftValue + rightValue})
+}
+
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+
+	switch operand {
+	case True:
+		return vm.push(False)
+	case False:
+		return vm.push(True)
+	case Null:
+		return vm.push(True)
+	default:
+		return vm.push(False)
+	}
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return vm.push(object.NegateInteger(operand))
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
+		return fmt.Errorf("unknown operator: -%s", operand.Type())
+	}
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY && index.Type() == object.INTEGER:
+		return vm.executeArrayIndex(left, index)
+
+	case left.Type() == object.HASH:
+		return vm.executeHashIndex(left, index)
+
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	arrayObject := array.(*object.Array)
+	i := index.(*object.Integer).Value
+	max := len(arrayObject.Elements) - 1
+
+	if index.(*object.Integer).IsBig() || i < 0 || i > max {
+		return vm.push(Null)
+	}
+
+	return vm.push(arrayObject.Elements[i])
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("object %s is not hashable", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+// executeSetIndex stores value in an array or hash, changing it in place
+func (vm *VM) executeSetIndex(left, index, value object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be INTEGER, got %s", index.Type())
+		}
+
+		if integer.IsBig() || integer.Value < 0 || integer.Value >= len(left.Elements) {
+			return fmt.Errorf("index out of range: %s with length %d", integer.Inspect(), len(left.Elements))
+		}
+
+		left.Elements[integer.Value] = value
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("object %s is not hashable", index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+
+	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+
+	return vm.push(value)
+}
+
+// buildArray creates an Array from the stack elements in [startIndex, endIndex)
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// buildHash creates a Hash from the alternating keys and values
+// on the stack in [startIndex, endIndex)
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hashedPairs := make(map[object.HashKey]object.HashPair)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("object %s is not hashable", key.Type())
+		}
+
+		hashedPairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: hashedPairs}, nil
+}
+
+// halt stops Run once the current instruction is done by moving
+// to the end of the main frame's instructions
+func (vm *VM) halt() {
+	vm.halted = true
+
+	frame := vm.currentFrame()
+	frame.ip = len(frame.Instructions()) - 1
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
 func (vm *VM) push(obj object.Object) error {
 	if vm.sp >= StackSize {
 		return fmt.Errorf("STACK OVERFLOW!!")
@@ -85,3 +820,24 @@ func (vm *VM) pop() object.Object {
 	vm.sp--
 	return o
 }
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+
+	return False
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+
+	case *object.Null:
+		return false
+
+	default:
+		return true
+	}
+}
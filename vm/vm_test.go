@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -21,11 +22,888 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"1", 1},
 		{"2", 2},
 		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"1 * 2", 2},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"-5", -5},
+		{"-10", -10},
+		{"-50 + 100 + -50", 0},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
 	}
 
 	runVmTests(t, tests)
 }
 
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"1180591620717411303424", "1180591620717411303424"},
+		{"1099511627776 * 1073741824", "1180591620717411303424"},
+		{"1180591620717411303424 / 1073741824", "1099511627776"},
+		{"1180591620717411303425 % 1024", "1"},
+		{"-1180591620717411303424", "-1180591620717411303424"},
+		{"1180591620717411303424 - 1180591620717411303423", "1"},
+		{"1180591620717411303424 > 9223372036854775807", "true"},
+		{"9223372036854775807 < 1180591620717411303424", "true"},
+		{"1180591620717411303424 == 1099511627776 * 1073741824", "true"},
+		{"1180591620717411303424 * 0.5", "5.902958103587057e+20"},
+		{"let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } }; factorial(25)", "15511210043330985984000000"},
+		{`{1180591620717411303424: "x"}[1099511627776 * 1073741824]`, "x"},
+		{"[1, 2][1180591620717411303424]", "null"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if got := vm.LastPoppedStackElem().Inspect(); got != tt.want {
+			t.Errorf("%s: got %s, wanted %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"5 - 0.5", 4.5},
+		{"2 * 1.25", 2.5},
+		{"1 / 4.0", 0.25},
+		{"(1.5 + 2) * 2", 7.0},
+		{"7.5 % 2", 1.5},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"3 >= 2", true},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"false != true", true},
+		{"(1 < 2) == true", true},
+		{"(1 < 2) == false", false},
+		{"(1 > 2) == true", false},
+		{"(1 > 2) == false", true},
+		{"5 == true", false},
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"true || false", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && \"a\"", true},
+		{"if (false) { 1 } || 0", true},
+		{"false && (1 + true)", false},
+		{"true || (1 + true)", true},
+		{"let x = 5; x > 1 && x < 10 || false", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 }", 20},
+		{"if (false) { 10 }", Null},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 }", Null},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (false) { 10 } else if (true) { 20 } else { 30 }", 20},
+		{"if (false) { 10 } else if (false) { 20 } else { 30 }", 30},
+		{"if (true) { 10 } else if (true) { 20 } else { 30 }", 10},
+		{"if (false) { 10 } else if (false) { 20 }", Null},
+		{"let x = 3; if (x == 1) { 1 } else if (x == 2) { 2 } else if (x == 3) { 3 } else { 4 }", 3},
+		{"let f = fn(x) { if (x < 0) { return -1; } else if (x == 0) { return 0; }; 1 }; [f(-5), f(0), f(5)]", []int{-1, 0, 1}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"monkey"`, "monkey"},
+		{`"mon" + "key"`, "monkey"},
+		{`"mon" + "key" + "banana"`, "monkeybanana"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1, 2, 3]", []int{1, 2, 3}},
+		{"[1 + 2, 3 + 4, 5 + 6]", []int{3, 7, 11}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			"{}", map[object.HashKey]int{},
+		},
+		{
+			"{1: 2, 2: 3}",
+			map[object.HashKey]int{
+				(&object.Integer{Value: 1}).HashKey(): 2,
+				(&object.Integer{Value: 2}).HashKey(): 3,
+			},
+		},
+		{
+			"{1 + 1: 2 + 2, 3 + 3: 4 + 4}",
+			map[object.HashKey]int{
+				(&object.Integer{Value: 2}).HashKey(): 4,
+				(&object.Integer{Value: 6}).HashKey(): 8,
+			},
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"[][0]", Null},
+		{"[1, 2, 3][99]", Null},
+		{"[1][-1]", Null},
+		{"{1: 1, 2: 2}[1]", 1},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{1: 1}[0]", Null},
+		{"{}[0]", Null},
+		{`{"one": 1, true: 2}["one"]`, 1},
+		{`{"one": 1, true: 2}[true]`, 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 2", 2},
+		{"let x = 1; let y = 0; y = x = 5; x + y", 10},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let i = 0; while (i < 10) { i += 1; }; i", 10},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; }; sum", 6},
+		{"let x = 1; let set = fn() { x = 2; }; set(); x", 2},
+		{"let x = 1; let f = fn() { let x = 5; x += 1; x }; f() + x", 7},
+		{"let f = fn(n) { n *= 2; n }; f(21)", 42},
+		{"let f = fn() { let total = 0; for (x in [1, 2, 3]) { total += x; }; total }; f()", 6},
+		{"let x = 1.5; x *= 2; x == 3.0", true},
+		{"let arr = [1, 2, 3]; arr[0] = 10; arr[0] + arr[2]", 13},
+		{"let arr = [1, 2, 3]; arr[1] = 5", 5},
+		{"let arr = [1, 2, 3]; arr[2] += 4; arr[2]", 7},
+		{"let arr = [[1, 2], [3, 4]]; arr[1][0] *= 10; arr[1][0]", 30},
+		{`let h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{`let h = {}; h["new"] = 3; h["new"]`, 3},
+		{`let h = {"n": 1}; h["n"] -= 5; h["n"]`, -4},
+		{`let h = {}; h[true] = 1; h[2] = 2; h[true] + h[2]`, 3},
+		// Arrays and hashes are changed in place, so every binding sees the change
+		{"let a = [1]; let b = a; b[0] = 2; a[0]", 2},
+		{"let set = fn(arr) { arr[0] = 9; }; let a = [1]; set(a); a[0]", 9},
+		{`let h = {"k": 0}; let inc = fn() { h["k"] += 1; }; inc(); inc(); h["k"]`, 2},
+		{"let arr = [0, 0, 0]; for (i in [0, 1, 2]) { arr[i] = i * i; }; arr[2]", 4},
+		{"let i = 0; let arr = [5, 6]; arr[i] += 1; i", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 10) { let i = i + 1; }; i", 10},
+		{"let i = 0; while (false) { let i = i + 1; }; i", 0},
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; }; sum", 6},
+		{"let n = 0; for (x in []) { let n = n + 1; }; n", 0},
+		{`let n = 0; for (c in "héllo") { let n = n + 1; }; n`, 5},
+		{`let sum = 0; for (k in {1: 10, 2: 20}) { let sum = sum + k; }; sum`, 3},
+		{"let keys = []; for (k in {3: 1, 1: 2, 2: 3}) { let keys = push(keys, k); }; keys", []int{1, 2, 3}},
+		{"let first = fn(arr) { for (x in arr) { if (x > 1) { return x; } }; -1 }; first([1, 5, 7])", 5},
+		{"let first = fn(arr) { for (x in arr) { if (x > 10) { return x; } }; -1 }; first([1, 5, 7])", -1},
+		{"let f = fn() { while (true) { return 3; } }; f()", 3},
+		{"let sum = fn(n) { let total = 0; let i = 0; while (i < n) { let i = i + 1; let total = total + i; }; total }; sum(10)", 55},
+		{"let f = fn() { for (x in [1]) { x } }; f()", Null},
+		{"if (true) { while (false) {} }", Null},
+		{"let i = 0; while (i < 100000) { let i = i + 1; }; i", 100000},
+		// break and continue in the middle of an expression abandon it
+		{"let r = 0; for (x in [1, 2]) { r = 1 + if (true) { continue; } else { 2 }; }; r", 0},
+		{"let r = 0; for (x in [1, 2, 3]) { let y = [1, if (x == 2) { continue; } else { x }]; r += y[1]; }; r", 4},
+		{"let r = 0; while (true) { r = len(if (true) { break; }); }; r", 0},
+		{"let n = 0; while (n < 5000) { n += 1; let y = 1 + if (true) { continue; } else { 2 }; }; n", 5000},
+		{"let f = fn() { 1 + if (true) { return 2; } else { 3 } }; f()", 2},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue; }; let sum = sum + x; }; sum", 4},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; }; let sum = sum + x; }; sum", 3},
+		{"let i = 0; let n = 0; while (i < 10) { let i = i + 1; if (i > 3) { continue; }; let n = n + 1; }; n", 3},
+		{"let n = 0; for (x in [1, 2]) { for (y in [1, 2, 3]) { if (y == 2) { break; }; let n = n + 1; }; }; n", 2},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { break; } }; 10 }; f()", 10},
+		{"let f = fn(arr) { let n = 0; for (x in arr) { if (x == 2) { continue; }; let n = n + x; }; n }; f([1, 2, 3])", 4},
+		// Breaking out of a for loop leaves nothing behind on the stack
+		{"let i = 0; while (i < 3000) { let i = i + 1; for (x in [1]) { break; } }; i", 3000},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"a" == "a"`, "unknown operator: STRING == STRING"},
+		{"true > false", "unknown operator: BOOLEAN > BOOLEAN"},
+		{"1 * true", "type mismatch: INTEGER * BOOLEAN"},
+		{`"Hello" + 1`, "type mismatch: STRING + INTEGER"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "object CLOSURE is not hashable"},
+		{`{fn(x) { x }: 1}`, "object CLOSURE is not hashable"},
+		{"1[0]", "index operator not supported: INTEGER"},
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"let zero = fn() { 0 }; 10 / zero()", "division by zero: 10 / 0"},
+		{"5 % 0", "modulo by zero: 5 % 0"},
+		{"5.5 % 0", "modulo by zero: 5.5 % 0"},
+		{"true >= false", "unknown operator: BOOLEAN >= BOOLEAN"},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1; x /= 0", "division by zero: 1 / 0"},
+		{"let arr = [1, 2, 3]; arr[3] = 4", "index out of range: 3 with length 3"},
+		{"let arr = [1, 2, 3]; arr[-1] = 4", "index out of range: -1 with length 3"},
+		{`let arr = [1]; arr["a"] = 4`, "array index must be INTEGER, got STRING"},
+		{"let h = {}; h[fn(x) { x }] = 1", "object CLOSURE is not hashable"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{`let h = {}; h["missing"] += 1`, "type mismatch: NULL + INTEGER"},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"if (false) { let y = 1; }; y", "identifier not found: y"},
+		{"let f = fn() { if (false) { let y = 1 }; y }; [1, 2, 3, 4]; f()", "identifier not found: y"},
+		{"let g = fn(a) { if (a) { let y = 99 }; y }; g(true); g(false)", "identifier not found: y"},
+		{"fn() { for (x in []) {}; x }()", "identifier not found: x"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"let double = fn(x) { x * 2 }; double(4611686018427387904)", "integer overflow: 4611686018427387904 * 2"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		checked := New(comp.ByteCode())
+		checked.SetCheckedArithmetic(true)
+
+		var runtimeErr *RuntimeError
+		if err := checked.Run(); !errors.As(err, &runtimeErr) {
+			t.Fatalf("%s: expected a RuntimeError, got %v", tt.input, err)
+		}
+
+		if runtimeErr.Message != tt.want {
+			t.Errorf("wrong VM error: got %q, wanted %q", runtimeErr.Message, tt.want)
+		}
+
+		// Unchecked it should promote to a big integer
+		promoting := New(comp.ByteCode())
+		if err := promoting.Run(); err != nil {
+			t.Fatalf("%s: unchecked arithmetic should not fail, got error %s", tt.input, err)
+		}
+
+		promoted, ok := promoting.LastPoppedStackElem().(*object.Integer)
+		if !ok || !promoted.IsBig() {
+			t.Errorf("%s: unchecked arithmetic should promote to a big integer, got %+v", tt.input, promoted)
+		}
+	}
+}
+
+func TestRuntimeErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"5 + true;", "test.monkey:1:3: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = -true;", "test.monkey:2:9: unknown operator: -BOOLEAN"},
+		{"let f = fn(x) {\n  x + true\n};\nf(1);", "test.monkey:2:5: type mismatch: INTEGER + BOOLEAN"},
+		{"len(1)", "test.monkey:1:4: argument to `len` not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.NewWithFilename("test.monkey", tt.input))
+		program := p.ParseProgram()
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q but got none", tt.input)
+		}
+
+		if err.Error() != tt.want {
+			t.Errorf("wrong VM error: got %q, wanted %q", err, tt.want)
+		}
+	}
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestProgramResult(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // Empty if there should be no result
+	}{
+		{"1 + 2", "3"},
+		{"", ""},
+		{"let x = 5;", ""},
+		{"for (x in [1]) { x }", ""},
+		{"while (false) {}", ""},
+		{"1; for (x in [1]) { break; }", ""},
+		{"let x = 1; while (true) { 1 + if (true) { break; } }", ""},
+		{"for (x in [1]) { x }; 2", "2"},
+		{"if (true) { return 4; }; let x = 1;", "4"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		got := ""
+		if result := vm.LastPoppedStackElem(); result != nil {
+			got = result.Inspect()
+		}
+
+		if got != tt.want {
+			t.Errorf("%q: wrong result: got %q, wanted %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTopLevelReturn(t *testing.T) {
+	tests := []vmTestCase{
+		{"return 5;", 5},
+		{"return 1; 2;", 1},
+		{"let x = 10; if (x > 5) { return x * 2; }; 0", 20},
+		{"for (x in [1, 2, 3]) { if (x == 2) { return x; } }; 0", 2},
+		{"let f = fn() { return 3; }; return f() + 1;", 4},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctionsWithoutArguments(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let fivePlusTen = fn() { 5 + 10; };
+			fivePlusTen();
+			`,
+			expected: 15,
+		},
+		{
+			input: `
+			let one = fn() { 1; };
+			let two = fn() { 2; };
+			one() + two()
+			`,
+			expected: 3,
+		},
+		{
+			input: `
+			let earlyExit = fn() { return 99; 100; };
+			earlyExit();
+			`,
+			expected: 99,
+		},
+		{
+			input: `
+			let noReturn = fn() { };
+			noReturn();
+			`,
+			expected: Null,
+		},
+		{
+			input: `
+			let returnsOne = fn() { 1; };
+			let returnsOneReturner = fn() { returnsOne; };
+			returnsOneReturner()();
+			`,
+			expected: 1,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctionsWithArgumentsAndBindings(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let identity = fn(a) { a; };
+			identity(4);
+			`,
+			expected: 4,
+		},
+		{
+			input: `
+			let sum = fn(a, b) { a + b; };
+			sum(1, 2);
+			`,
+			expected: 3,
+		},
+		{
+			input: `
+			let sum = fn(a, b) {
+				let c = a + b;
+				c;
+			};
+			let outer = fn() {
+				sum(1, 2) + sum(3, 4);
+			};
+			outer();
+			`,
+			expected: 10,
+		},
+		{
+			input: `
+			let globalNum = 10;
+
+			let sum = fn(a, b) {
+				let c = a + b;
+				c + globalNum;
+			};
+
+			let outer = fn() {
+				sum(1, 2) + sum(3, 4) + globalNum;
+			};
+
+			outer() + globalNum;
+			`,
+			expected: 50,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let countUp = fn(x) {
+				if (x > 9) {
+					return x;
+				}
+				countUp(x + 1);
+			};
+			countUp(0);
+			`,
+			expected: 10,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let newClosure = fn(a) {
+				fn() { a; };
+			};
+			let closure = newClosure(99);
+			closure();
+			`,
+			expected: 99,
+		},
+		{
+			input: `
+			let newAdder = fn(a, b) {
+				fn(c) { a + b + c };
+			};
+			let adder = newAdder(1, 2);
+			adder(8);
+			`,
+			expected: 11,
+		},
+		{
+			input: `
+			let newAdderOuter = fn(a, b) {
+				let c = a + b;
+				fn(d) {
+					let e = d + c;
+					fn(f) { e + f; };
+				};
+			};
+			let newAdderInner = newAdderOuter(1, 2)
+			let adder = newAdderInner(3);
+			adder(8);
+			`,
+			expected: 14,
+		},
+		{
+			input: `
+			let compose = fn(f, g) {
+				fn(x) { f(g(x)) };
+			};
+			let addOne = fn(x) { x + 1 };
+			let addTwo = fn(x) { x + 2 };
+			compose(addOne, addTwo)(1);
+			`,
+			expected: 4,
+		},
+		{
+			input: `
+			let newCounter = fn() {
+				let c = 0;
+				fn() { c += 1; c }
+			};
+			let counter = newCounter();
+			counter();
+			counter();
+			`,
+			expected: 2,
+		},
+		{
+			input: `
+			let f = fn(start) {
+				let inc = fn() { start += 1; };
+				let get = fn() { start };
+				inc();
+				inc();
+				[start, get()]
+			};
+			f(10);
+			`,
+			expected: []int{12, 12},
+		},
+		{
+			input: `
+			let f = fn() {
+				let x = 1;
+				let get = fn() { x };
+				x = 5;
+				get()
+			};
+			f();
+			`,
+			expected: 5,
+		},
+		{
+			input: `
+			let f = fn() {
+				let total = 0;
+				let add = fn(n) { fn() { total += n; } };
+				for (x in [1, 2, 3]) { add(x)(); }
+				total
+			};
+			f();
+			`,
+			expected: 6,
+		},
+		{
+			input: `
+			let f = fn() {
+				let getters = [];
+				for (x in [1, 2, 3]) { getters = push(getters, fn() { x }); }
+				[getters[0](), getters[2]()]
+			};
+			f();
+			`,
+			expected: []int{3, 3},
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let wrapper = fn() {
+				let countUp = fn(x) {
+					if (x > 9) {
+						return x;
+					}
+					countUp(x + 1);
+				};
+				countUp(0);
+			};
+			wrapper();
+			`,
+			expected: 10,
+		},
+		{
+			input: `
+			let repeat = fn(f, times) {
+				let iter = fn(acc, n) {
+					if (times > n) {
+						iter(f(acc), n + 1)
+					} else {
+						acc
+					}
+				};
+				iter(0, 0);
+			};
+			repeat(fn(x) { x + 2 }, 5);
+			`,
+			expected: 10,
+		},
+		{
+			input: `
+			let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };
+			let copy = count;
+			count = fn(n) { 100 };
+			copy(3);
+			`,
+			expected: 101,
+		},
+		{
+			input: `
+			let outer = fn() {
+				let countDown = fn(x) { if (x == 0) { 0 } else { countDown(x - 1) } };
+				let first = countDown(3);
+				countDown = fn(x) { 7 };
+				[first, countDown(1)]
+			};
+			outer();
+			`,
+			expected: []int{0, 7},
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, Null},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, Null},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, Null},
+		{`push([], 1)`, []int{1}},
+	}
+
+	runVmTests(t, tests)
+
+	errorTests := []vmTestCase{
+		{`len(1)`, "argument to `len` not supported: INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments: got 2, wanted 1"},
+		{`first(1)`, "argument to `first` must be an ARRAY, got INTEGER"},
+		{`last(1)`, "argument to `last` must be an ARRAY, got INTEGER"},
+		{`push(1, 1)`, "argument to `push` must be an ARRAY, got INTEGER"},
+	}
+
+	runVmErrorTests(t, errorTests)
+}
+
+func TestHigherOrderFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let map = fn(arr, f) {
+				let iter = fn(arr, accumulated) {
+					if (len(arr) > 0) {
+						iter(rest(arr), push(accumulated, f(first(arr))));
+					} else {
+						accumulated
+					}
+				};
+				iter(arr, []);
+			};
+			let double = fn(x) { x + x };
+			map([1, 2, 3, 4], double);
+			`,
+			expected: []int{2, 4, 6, 8},
+		},
+		{
+			input: `
+			let reduce = fn(arr, initial, f) {
+				let iter = fn(arr, result) {
+					if (len(arr) > 0) {
+						iter(rest(arr), f(result, first(arr)));
+					} else {
+						result
+					}
+				};
+				iter(arr, initial);
+			};
+			let sum = fn(arr) {
+				reduce(arr, 0, fn(initial, el) { initial + el });
+			};
+			sum([1, 2, 3, 4, 5]);
+			`,
+			expected: 15,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalsStorePersists(t *testing.T) {
+	globals := make([]object.Object, GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+	constants := []object.Object{}
+
+	for _, tt := range []vmTestCase{
+		{"let a = 1; a", 1},
+		{"let b = a + 1; b", 2},
+		{"a + b", 3},
+	} {
+		comp := compiler.NewWithState(symbolTable, constants)
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := comp.ByteCode()
+		constants = bytecode.Constants
+
+		vm := NewWithGlobalsStore(bytecode, globals)
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		testExpectedObject(t, tt.expected, vm.LastPoppedStackElem())
+	}
+}
+
+func TestCallingFunctionsWithWrongArguments(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input:    "fn() { 1; }(1);",
+			expected: "wrong number of arguments: got 1, wanted 0",
+		},
+		{
+			input:    "fn(a) { a; }();",
+			expected: "wrong number of arguments: got 0, wanted 1",
+		},
+		{
+			input:    "fn(a, b) { a + b; }(1);",
+			expected: "wrong number of arguments: got 1, wanted 2",
+		},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -44,12 +922,43 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 			t.Fatalf("vm error: %s", err)
 		}
 
-		stackElem := vm.StackTop()
+		stackElem := vm.LastPoppedStackElem()
 
 		testExpectedObject(t, tt.expected, stackElem)
 	}
 }
 
+// runVmErrorTests is like runVmTests but expects each program
+// to fail at runtime with the error message in tt.expected
+func runVmErrorTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q but got none", tt.input)
+		}
+
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("VM error is not a RuntimeError: got %T (%v)", err, err)
+		}
+
+		if runtimeErr.Message != tt.expected {
+			t.Errorf("wrong VM error: got %q, wanted %q", runtimeErr.Message, tt.expected)
+		}
+	}
+}
+
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -69,6 +978,19 @@ func testIntegerObject(expected int, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not a Float, got %T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value: got %g, wanted %g", result.Value, expected)
+	}
+
+	return nil
+}
+
 func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
 	t.Helper()
 
@@ -78,5 +1000,97 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
+
+	case bool:
+		err := testBooleanObject(expected, actual)
+		if err != nil {
+			t.Errorf("testBooleanObject failed: %s", err)
+		}
+
+	case string:
+		err := testStringObject(expected, actual)
+		if err != nil {
+			t.Errorf("testStringObject failed: %s", err)
+		}
+
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: got %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong number of elements: got %d, wanted %d", len(array.Elements), len(expected))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			err := testIntegerObject(expectedElem, array.Elements[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+
+	case map[object.HashKey]int:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash: got %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("hash has wrong number of pairs: got %d, wanted %d", len(hash.Pairs), len(expected))
+			return
+		}
+
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+			}
+
+			err := testIntegerObject(expectedValue, pair.Value)
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: got %T (%+v)", actual, actual)
+		}
 	}
 }
+
+func testBooleanObject(expected bool, actual object.Object) error {
+	result, ok := actual.(*object.Boolean)
+	if !ok {
+		return fmt.Errorf("object is not a Boolean, got %T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value: got %t, wanted %t", result.Value, expected)
+	}
+
+	return nil
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not a String, got %T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value: got %q, wanted %q", result.Value, expected)
+	}
+
+	return nil
+}